@@ -94,6 +94,17 @@ func (g *factoryGenerator) GenerateType(c *generator.Context, t *types.Type, w i
 		"xnsNamespaceSet":                c.Universe.Type(xnsNamespaceSet),
 		"xnsNewNamespaceSet":             c.Universe.Type(xnsNewNamespaceSet),
 		"genericInformer":                c.Universe.Type(types.Name{Package: g.informersPackage, Name: "GenericInformer"}),
+		"xnsRestoreSnapshots":            c.Universe.Function(xnsRestoreSnapshots),
+		"xnsSaveSnapshots":               c.Universe.Function(xnsSaveSnapshots),
+		"xnsSnapshotFileName":            c.Universe.Function(xnsSnapshotFileName),
+		"xnsSyncReport":                  c.Universe.Type(xnsSyncReport),
+		"xnsWaitForCacheSyncTimeout":     c.Universe.Function(xnsWaitForCacheSyncTimeout),
+		"xnsPrioritizedNamespaceSet":     c.Universe.Type(xnsPrioritizedNamespaceSet),
+		"xnsWaitForPrioritySync":         c.Universe.Function(xnsWaitForPrioritySync),
+		"cacheTransformFunc":             c.Universe.Type(cacheTransformFunc),
+		"xnsInternTable":                 c.Universe.Type(xnsInternTable),
+		"xnsInternMetadataTransform":     c.Universe.Function(xnsInternMetadataTransform),
+		"klogErrorf":                     c.Universe.Function(klogErrorf),
 	}
 
 	sw.Do(sharedInformerFactoryStruct, m)
@@ -113,6 +124,7 @@ type sharedInformerFactory struct {
 	lock {{.syncMutex|raw}}
 	defaultResync {{.timeDuration|raw}}
 	customResync map[{{.reflectType|raw}}]{{.timeDuration|raw}}
+	transform {{.cacheTransformFunc|raw}}
 
 	informers map[{{.reflectType|raw}}]{{.cacheSharedIndexInformer|raw}}
 	// startedInformers is used for tracking which informers have been started.
@@ -143,6 +155,20 @@ func WithTweakListOptions(tweakListOptions internalinterfaces.TweakListOptionsFu
 	}
 }
 
+// WithTransform sets a transform on all informers.
+func WithTransform(transform {{.cacheTransformFunc|raw}}) SharedInformerOption {
+	return func(factory *sharedInformerFactory) *sharedInformerFactory {
+		factory.transform = transform
+		return factory
+	}
+}
+
+// WithInterning makes all informers intern common metadata strings through the
+// given table, so objects in different caches share them.
+func WithInterning(table *{{.xnsInternTable|raw}}) SharedInformerOption {
+	return WithTransform({{.xnsInternMetadataTransform|raw}}(table))
+}
+
 // WithNamespaces limits the SharedInformerFactory to the specified namespaces.
 func WithNamespaces(namespaces ...string) SharedInformerOption {
 	return func(factory *sharedInformerFactory) *sharedInformerFactory {
@@ -151,6 +177,25 @@ func WithNamespaces(namespaces ...string) SharedInformerOption {
 	}
 }
 
+// WithNamespacePriorities limits the SharedInformerFactory to the namespaces in
+// the given map, which informers start and sync in order of priority, highest
+// first.
+func WithNamespacePriorities(priorities map[string]int) SharedInformerOption {
+	return func(factory *sharedInformerFactory) *sharedInformerFactory {
+		if p, ok := factory.namespaces.({{.xnsPrioritizedNamespaceSet|raw}}); ok {
+			p.SetPriorities(priorities)
+		}
+
+		namespaces := make([]string, 0, len(priorities))
+		for namespace := range priorities {
+			namespaces = append(namespaces, namespace)
+		}
+
+		factory.SetNamespaces(namespaces)
+		return factory
+	}
+}
+
 // NewSharedInformerFactory constructs a new instance of sharedInformerFactory for all namespaces.
 func NewSharedInformerFactory(client {{.clientSetInterface|raw}}, defaultResync {{.timeDuration|raw}}) SharedInformerFactory {
 	return NewSharedInformerFactoryWithOptions(client, defaultResync)
@@ -239,6 +284,79 @@ func (f *sharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[ref
         return res
 }
 
+// WaitForCacheSyncWithTimeout waits for all started informers' caches to sync
+// until the timeout expires, and returns a report for each of them.  If
+// markDegraded is true, namespaces that haven't synced by then are marked
+// degraded, so the informers report synced for the remaining namespaces.
+func (f *sharedInformerFactory) WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout {{.timeDuration|raw}},
+	markDegraded bool,
+) map[{{.reflectType|raw}}]{{.xnsSyncReport|raw}} {
+	syncInformers := func() map[{{.reflectType|raw}}]{{.cacheSharedIndexInformer|raw}} {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		syncInformers := map[{{.reflectType|raw}}]{{.cacheSharedIndexInformer|raw}}{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				syncInformers[informerType] = informer
+			}
+		}
+		return syncInformers
+	}()
+
+	return {{.xnsWaitForCacheSyncTimeout|raw}}(stopCh, timeout, markDegraded, syncInformers)
+}
+
+// WaitForCacheSyncWithPriority waits for the namespaces with at least the given
+// priority to sync in all started informers, ignoring lower priority ones.
+func (f *sharedInformerFactory) WaitForCacheSyncWithPriority(stopCh <-chan struct{}, minPriority int) map[{{.reflectType|raw}}]bool {
+	syncInformers := func() map[{{.reflectType|raw}}]{{.cacheSharedIndexInformer|raw}} {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		syncInformers := map[{{.reflectType|raw}}]{{.cacheSharedIndexInformer|raw}}{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				syncInformers[informerType] = informer
+			}
+		}
+		return syncInformers
+	}()
+
+	return {{.xnsWaitForPrioritySync|raw}}(stopCh, minPriority, syncInformers)
+}
+
+// SaveSnapshots writes a snapshot of each started informer's caches to a file
+// in the given directory.
+func (f *sharedInformerFactory) SaveSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshotInformers := map[string]{{.cacheSharedIndexInformer|raw}}{}
+	for informerType, informer := range f.informers {
+		if f.startedInformers[informerType] {
+			snapshotInformers[{{.xnsSnapshotFileName|raw}}(informerType)] = informer
+		}
+	}
+
+	return {{.xnsSaveSnapshots|raw}}(dir, snapshotInformers)
+}
+
+// RestoreSnapshots restores informers from the snapshots in the given directory
+// written by SaveSnapshots.  It must be called after the informers have been
+// requested and before the factory is started.
+func (f *sharedInformerFactory) RestoreSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshotInformers := map[string]{{.cacheSharedIndexInformer|raw}}{}
+	for informerType, informer := range f.informers {
+		snapshotInformers[{{.xnsSnapshotFileName|raw}}(informerType)] = informer
+	}
+
+	return {{.xnsRestoreSnapshots|raw}}(dir, snapshotInformers)
+}
+
 // InternalInformerFor returns the SharedIndexInformer for obj using an internal
 // client.
 func (f *sharedInformerFactory) InformerFor(obj {{.runtimeObject|raw}}, newFunc {{.interfacesNewInformerFunc|raw}}) {{.cacheSharedIndexInformer|raw}} {
@@ -257,6 +375,11 @@ func (f *sharedInformerFactory) InformerFor(obj {{.runtimeObject|raw}}, newFunc
   }
 
   informer = newFunc(f.client, resyncPeriod)
+  if f.transform != nil {
+    if err := informer.SetTransform(f.transform); err != nil {
+      {{.klogErrorf|raw}}("Failed to set transform for %v informer: %v", informerType, err)
+    }
+  }
   f.informers[informerType] = informer
 
   return informer
@@ -314,6 +437,31 @@ type SharedInformerFactory interface {
 	// or the stop channel gets closed.
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
+	// WaitForCacheSyncWithTimeout waits for all started informers' caches to
+	// sync until the timeout expires or the stop channel gets closed, and
+	// returns a report for each informer.  If markDegraded is true, the
+	// namespaces that haven't synced by then are marked degraded: informers
+	// report they have synced for the remaining namespaces, while the
+	// degraded ones keep syncing in the background.
+	WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout {{.timeDuration|raw}}, markDegraded bool) map[reflect.Type]{{.xnsSyncReport|raw}}
+
+	// WaitForCacheSyncWithPriority blocks until the namespaces with at least
+	// the given priority have synced in all started informers, or the stop
+	// channel gets closed.
+	WaitForCacheSyncWithPriority(stopCh <-chan struct{}, minPriority int) map[reflect.Type]bool
+
+	// SaveSnapshots writes a snapshot of each started informer's caches to a
+	// file in the given directory.
+	SaveSnapshots(dir string) error
+
+	// RestoreSnapshots restores informers from the snapshots in the given
+	// directory written by SaveSnapshots, so they only need to watch for
+	// changes since the snapshots were taken.  It must be called after the
+	// informers have been requested and before the factory is started.
+	// Informers whose snapshot is missing or can't be used list everything
+	// as usual.
+	RestoreSnapshots(dir string) error
+
 	// ForResource gives generic access to a shared informer of the matching type.
 	ForResource(resource {{.schemaGroupVersionResource|raw}}) ({{.genericInformer|raw}}, error)
 
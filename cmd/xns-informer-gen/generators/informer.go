@@ -79,6 +79,7 @@ func (g *informerGenerator) GenerateType(c *generator.Context, t *types.Type, w
 		"apiScheme":                       c.Universe.Type(apiScheme),
 		"cacheIndexers":                   c.Universe.Type(cacheIndexers),
 		"cacheListWatch":                  c.Universe.Type(cacheListWatch),
+		"cacheListerWatcher":              c.Universe.Type(cacheListerWatcher),
 		"cacheMetaNamespaceIndexFunc":     c.Universe.Function(cacheMetaNamespaceIndexFunc),
 		"cacheNamespaceIndex":             c.Universe.Variable(cacheNamespaceIndex),
 		"cacheNewSharedIndexInformer":     c.Universe.Function(cacheNewSharedIndexInformer),
@@ -100,7 +101,7 @@ func (g *informerGenerator) GenerateType(c *generator.Context, t *types.Type, w
 		"version":                         namer.IC(g.groupVersion.Version.String()),
 		"watchInterface":                  c.Universe.Type(watchInterface),
 		"xnsNamespaceSet":                 c.Universe.Type(xnsNamespaceSet),
-		"xnsNewMultiNamespaceInformer":    c.Universe.Type(xnsNewMultiNamespaceInformer),
+		"xnsNewMultiNamespaceInformer":    c.Universe.Function(xnsNewListerWatcherInformer),
 	}
 
 	sw.Do(typeInformerInterface, m)
@@ -147,30 +148,36 @@ var typeFilteredInformerPublicConstructor = `
 // one. This reduces memory footprint and number of connections to the server.
 func NewFiltered$.type|public$Informer(client $.clientSetInterface|raw$$if .namespaced$, namespaces $.xnsNamespaceSet|raw$$end$,
 	resyncPeriod $.timeDuration|raw$, indexers $.cacheIndexers|raw$, tweakListOptions $.interfacesTweakListOptionsFunc|raw$) $.cacheSharedIndexInformer|raw$ {
-    $- if .namespaced$newInformer := func(namespace string) $.cacheSharedIndexInformer|raw$ {$end$
-        return $.cacheNewSharedIndexInformer|raw$(
-   		    &$.cacheListWatch|raw${
-			    ListFunc: func(options $.v1ListOptions|raw$) ($.runtimeObject|raw$, error) {
-				    if tweakListOptions != nil {
-					    tweakListOptions(&options)
-				    }
-				    return client.$.group$$.version$().$.type|publicPlural$($if .namespaced$namespace$end$).List(context.TODO(), options)
-			    },
-			    WatchFunc: func(options $.v1ListOptions|raw$) ($.watchInterface|raw$, error) {
-				    if tweakListOptions != nil {
-					    tweakListOptions(&options)
-				    }
-				    return client.$.group$$.version$().$.type|publicPlural$($if .namespaced$namespace$end$).Watch(context.TODO(), options)
-			    },
-		    },
-		    &$.type|raw${},
-		    resyncPeriod,
-		    indexers,
-	    )
 $- if .namespaced$
-    }
+	newListerWatcher := func(namespace string) $.cacheListerWatcher|raw$ {
+		return &$.cacheListWatch|raw${
+$- else$
+	return $.cacheNewSharedIndexInformer|raw$(
+		&$.cacheListWatch|raw${
+$- end$
+			ListFunc: func(options $.v1ListOptions|raw$) ($.runtimeObject|raw$, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.$.group$$.version$().$.type|publicPlural$($if .namespaced$namespace$end$).List(context.TODO(), options)
+			},
+			WatchFunc: func(options $.v1ListOptions|raw$) ($.watchInterface|raw$, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.$.group$$.version$().$.type|publicPlural$($if .namespaced$namespace$end$).Watch(context.TODO(), options)
+			},
+$- if .namespaced$
+		}
+	}
 
-    return $.xnsNewMultiNamespaceInformer|raw$(namespaces, resyncPeriod, newInformer)
+	return $.xnsNewMultiNamespaceInformer|raw$(namespaces, &$.type|raw${}, resyncPeriod, indexers, newListerWatcher)
+$- else$
+		},
+		&$.type|raw${},
+		resyncPeriod,
+		indexers,
+	)
 $- end$
 }
 `
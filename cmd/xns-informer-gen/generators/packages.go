@@ -95,6 +95,30 @@ func vendorless(p string) string {
 	return p
 }
 
+// importNames holds the local names of imports whose last path element isn't
+// their package name, such as the major version of a module.
+var importNames = map[string]string{
+	"k8s.io/klog/v2": "klog",
+}
+
+// newImportTracker returns an import tracker that uses importNames, falling
+// back to gengo's usual names.
+func newImportTracker() namer.ImportTracker {
+	tracker := generator.NewImportTracker().(*namer.DefaultImportTracker)
+	localName := tracker.LocalName
+	tracker.LocalName = func(name types.Name) string {
+		if local, ok := importNames[name.Package]; ok {
+			if _, found := tracker.PathOf(local); !found {
+				return local
+			}
+		}
+
+		return localName(name)
+	}
+
+	return tracker
+}
+
 // Packages makes the client package definition.
 func Packages(context *generator.Context, arguments *args.GeneratorArgs) generator.Packages {
 	boilerplate, err := arguments.LoadGoBoilerplate()
@@ -259,7 +283,7 @@ func factoryPackage(basePackage, clientSetPackage, informersPackage string, boil
 					},
 					outputPackage:             basePackage,
 					informersPackage:          informersPackage,
-					imports:                   generator.NewImportTracker(),
+					imports:                   newImportTracker(),
 					groupVersions:             groupVersions,
 					clientSetPackage:          clientSetPackage,
 					internalInterfacesPackage: packageForInternalInterfaces(informersPackage),
@@ -272,7 +296,7 @@ func factoryPackage(basePackage, clientSetPackage, informersPackage string, boil
 					},
 					outputPackage:           basePackage,
 					informersPackage:        informersPackage,
-					imports:                 generator.NewImportTracker(),
+					imports:                 newImportTracker(),
 					groupVersions:           groupVersions,
 					pluralExceptions:        pluralExceptions,
 					typesForGroupVersion:    typesForGroupVersion,
@@ -298,7 +322,7 @@ func factoryInterfacePackage(basePackage string, boilerplate []byte, clientSetPa
 					OptionalName: "factory_interfaces",
 				},
 				outputPackage:    packagePath,
-				imports:          generator.NewImportTracker(),
+				imports:          newImportTracker(),
 				clientSetPackage: clientSetPackage,
 			})
 
@@ -324,7 +348,7 @@ func groupPackage(
 				},
 				outputPackage:             packagePath,
 				groupVersions:             groupVersions,
-				imports:                   generator.NewImportTracker(),
+				imports:                   newImportTracker(),
 				generateGroupInterface:    generateInterfaces,
 				groupInterfacePackage:     filepath.Join(informersPackage, groupVersions.PackageName),
 				internalInterfacesPackage: packageForInternalInterfaces(informersPackage),
@@ -356,7 +380,7 @@ func versionPackage(basePackage, clientSetPackage, informersPackage, listersPack
 				groupVersionPackage:       filepath.Join(informersPackage, groupPkgName, gv.Version.String()),
 				generateVersionInterface:  generateInterfaces,
 				internalInterfacesPackage: packageForInternalInterfaces(informersPackage),
-				imports:                   generator.NewImportTracker(),
+				imports:                   newImportTracker(),
 				types:                     typesToGenerate,
 			})
 
@@ -370,7 +394,7 @@ func versionPackage(basePackage, clientSetPackage, informersPackage, listersPack
 					groupVersion:              gv,
 					groupGoName:               groupGoName,
 					typeToGenerate:            t,
-					imports:                   generator.NewImportTracker(),
+					imports:                   newImportTracker(),
 					clientSetPackage:          clientSetPackage,
 					listersPackage:            listersPackage,
 					internalInterfacesPackage: packageForInternalInterfaces(informersPackage),
@@ -23,11 +23,13 @@ var (
 	cacheGenericLister          = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "GenericLister"}
 	cacheIndexers               = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "Indexers"}
 	cacheListWatch              = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "ListWatch"}
+	cacheListerWatcher          = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "ListerWatcher"}
 	cacheMetaNamespaceIndexFunc = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "MetaNamespaceIndexFunc"}
 	cacheNamespaceIndex         = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "NamespaceIndex"}
 	cacheNewGenericLister       = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "NewGenericLister"}
 	cacheNewSharedIndexInformer = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "NewSharedIndexInformer"}
 	cacheSharedIndexInformer    = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "SharedIndexInformer"}
+	cacheTransformFunc          = types.Name{Package: "k8s.io/client-go/tools/cache", Name: "TransformFunc"}
 	listOptions                 = types.Name{Package: "k8s.io/kubernetes/pkg/apis/core", Name: "ListOptions"}
 	reflectType                 = types.Name{Package: "reflect", Name: "Type"}
 	runtimeObject               = types.Name{Package: "k8s.io/apimachinery/pkg/runtime", Name: "Object"}
@@ -39,8 +41,18 @@ var (
 	metav1NamespaceAll          = types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "NamespaceAll"}
 	metav1Object                = types.Name{Package: "k8s.io/apimachinery/pkg/apis/meta/v1", Name: "Object"}
 	watchInterface              = types.Name{Package: "k8s.io/apimachinery/pkg/watch", Name: "Interface"}
+	klogErrorf                  = types.Name{Package: "k8s.io/klog/v2", Name: "Errorf"}
 
-	xnsNamespaceSet              = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NamespaceSet"}
-	xnsNewNamespaceSet           = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NewNamespaceSet"}
-	xnsNewMultiNamespaceInformer = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NewMultiNamespaceInformer"}
+	xnsNamespaceSet             = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NamespaceSet"}
+	xnsNewNamespaceSet          = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NewNamespaceSet"}
+	xnsNewListerWatcherInformer = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "NewMultiNamespaceInformerWithListerWatcher"}
+	xnsRestoreSnapshots         = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "RestoreSnapshots"}
+	xnsSaveSnapshots            = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "SaveSnapshots"}
+	xnsSnapshotFileName         = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "SnapshotFileName"}
+	xnsSyncReport               = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "SyncReport"}
+	xnsWaitForCacheSyncTimeout  = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "WaitForCacheSyncWithTimeout"}
+	xnsPrioritizedNamespaceSet  = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "PrioritizedNamespaceSet"}
+	xnsWaitForPrioritySync      = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "WaitForPrioritySync"}
+	xnsInternTable              = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "InternTable"}
+	xnsInternMetadataTransform  = types.Name{Package: "github.com/maistra/xns-informer/pkg/informers", Name: "InternMetadataTransform"}
 )
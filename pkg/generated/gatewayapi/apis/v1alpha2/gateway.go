@@ -59,29 +59,24 @@ func NewGatewayInformer(client versioned.Interface, namespaces informers.Namespa
 // one. This reduces memory footprint and number of connections to the server.
 func NewFilteredGatewayInformer(client versioned.Interface, namespaces informers.NamespaceSet,
 	resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
-	newInformer := func(namespace string) cache.SharedIndexInformer {
-		return cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
-					if tweakListOptions != nil {
-						tweakListOptions(&options)
-					}
-					return client.GatewayV1alpha2().Gateways(namespace).List(context.TODO(), options)
-				},
-				WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
-					if tweakListOptions != nil {
-						tweakListOptions(&options)
-					}
-					return client.GatewayV1alpha2().Gateways(namespace).Watch(context.TODO(), options)
-				},
+	newListerWatcher := func(namespace string) cache.ListerWatcher {
+		return &cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.GatewayV1alpha2().Gateways(namespace).List(context.TODO(), options)
 			},
-			&apisv1alpha2.Gateway{},
-			resyncPeriod,
-			indexers,
-		)
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.GatewayV1alpha2().Gateways(namespace).Watch(context.TODO(), options)
+			},
+		}
 	}
 
-	return informers.NewMultiNamespaceInformer(namespaces, resyncPeriod, newInformer)
+	return informers.NewMultiNamespaceInformerWithListerWatcher(namespaces, &apisv1alpha2.Gateway{}, resyncPeriod, indexers, newListerWatcher)
 }
 
 func (f *gatewayInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
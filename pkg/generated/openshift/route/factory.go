@@ -33,6 +33,7 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
 )
 
 // SharedInformerOption defines the functional option type for SharedInformerFactory.
@@ -45,6 +46,7 @@ type sharedInformerFactory struct {
 	lock             sync.Mutex
 	defaultResync    time.Duration
 	customResync     map[reflect.Type]time.Duration
+	transform        cache.TransformFunc
 
 	informers map[reflect.Type]cache.SharedIndexInformer
 	// startedInformers is used for tracking which informers have been started.
@@ -75,6 +77,20 @@ func WithTweakListOptions(tweakListOptions internalinterfaces.TweakListOptionsFu
 	}
 }
 
+// WithTransform sets a transform on all informers.
+func WithTransform(transform cache.TransformFunc) SharedInformerOption {
+	return func(factory *sharedInformerFactory) *sharedInformerFactory {
+		factory.transform = transform
+		return factory
+	}
+}
+
+// WithInterning makes all informers intern common metadata strings through the
+// given table, so objects in different caches share them.
+func WithInterning(table *informers.InternTable) SharedInformerOption {
+	return WithTransform(informers.InternMetadataTransform(table))
+}
+
 // WithNamespaces limits the SharedInformerFactory to the specified namespaces.
 func WithNamespaces(namespaces ...string) SharedInformerOption {
 	return func(factory *sharedInformerFactory) *sharedInformerFactory {
@@ -83,6 +99,25 @@ func WithNamespaces(namespaces ...string) SharedInformerOption {
 	}
 }
 
+// WithNamespacePriorities limits the SharedInformerFactory to the namespaces in
+// the given map, which informers start and sync in order of priority, highest
+// first.
+func WithNamespacePriorities(priorities map[string]int) SharedInformerOption {
+	return func(factory *sharedInformerFactory) *sharedInformerFactory {
+		if p, ok := factory.namespaces.(informers.PrioritizedNamespaceSet); ok {
+			p.SetPriorities(priorities)
+		}
+
+		namespaces := make([]string, 0, len(priorities))
+		for namespace := range priorities {
+			namespaces = append(namespaces, namespace)
+		}
+
+		factory.SetNamespaces(namespaces)
+		return factory
+	}
+}
+
 // NewSharedInformerFactory constructs a new instance of sharedInformerFactory for all namespaces.
 func NewSharedInformerFactory(client versioned.Interface, defaultResync time.Duration) SharedInformerFactory {
 	return NewSharedInformerFactoryWithOptions(client, defaultResync)
@@ -170,6 +205,79 @@ func (f *sharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) map[ref
 	return res
 }
 
+// WaitForCacheSyncWithTimeout waits for all started informers' caches to sync
+// until the timeout expires, and returns a report for each of them.  If
+// markDegraded is true, namespaces that haven't synced by then are marked
+// degraded, so the informers report synced for the remaining namespaces.
+func (f *sharedInformerFactory) WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout time.Duration,
+	markDegraded bool,
+) map[reflect.Type]informers.SyncReport {
+	syncInformers := func() map[reflect.Type]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		syncInformers := map[reflect.Type]cache.SharedIndexInformer{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				syncInformers[informerType] = informer
+			}
+		}
+		return syncInformers
+	}()
+
+	return informers.WaitForCacheSyncWithTimeout(stopCh, timeout, markDegraded, syncInformers)
+}
+
+// WaitForCacheSyncWithPriority waits for the namespaces with at least the given
+// priority to sync in all started informers, ignoring lower priority ones.
+func (f *sharedInformerFactory) WaitForCacheSyncWithPriority(stopCh <-chan struct{}, minPriority int) map[reflect.Type]bool {
+	syncInformers := func() map[reflect.Type]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		syncInformers := map[reflect.Type]cache.SharedIndexInformer{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				syncInformers[informerType] = informer
+			}
+		}
+		return syncInformers
+	}()
+
+	return informers.WaitForPrioritySync(stopCh, minPriority, syncInformers)
+}
+
+// SaveSnapshots writes a snapshot of each started informer's caches to a file
+// in the given directory.
+func (f *sharedInformerFactory) SaveSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshotInformers := map[string]cache.SharedIndexInformer{}
+	for informerType, informer := range f.informers {
+		if f.startedInformers[informerType] {
+			snapshotInformers[informers.SnapshotFileName(informerType)] = informer
+		}
+	}
+
+	return informers.SaveSnapshots(dir, snapshotInformers)
+}
+
+// RestoreSnapshots restores informers from the snapshots in the given directory
+// written by SaveSnapshots.  It must be called after the informers have been
+// requested and before the factory is started.
+func (f *sharedInformerFactory) RestoreSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	snapshotInformers := map[string]cache.SharedIndexInformer{}
+	for informerType, informer := range f.informers {
+		snapshotInformers[informers.SnapshotFileName(informerType)] = informer
+	}
+
+	return informers.RestoreSnapshots(dir, snapshotInformers)
+}
+
 // InternalInformerFor returns the SharedIndexInformer for obj using an internal
 // client.
 func (f *sharedInformerFactory) InformerFor(obj runtime.Object, newFunc internalinterfaces.NewInformerFunc) cache.SharedIndexInformer {
@@ -188,6 +296,11 @@ func (f *sharedInformerFactory) InformerFor(obj runtime.Object, newFunc internal
 	}
 
 	informer = newFunc(f.client, resyncPeriod)
+	if f.transform != nil {
+		if err := informer.SetTransform(f.transform); err != nil {
+			klog.Errorf("Failed to set transform for %v informer: %v", informerType, err)
+		}
+	}
 	f.informers[informerType] = informer
 
 	return informer
@@ -242,6 +355,31 @@ type SharedInformerFactory interface {
 	// or the stop channel gets closed.
 	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
 
+	// WaitForCacheSyncWithTimeout waits for all started informers' caches to
+	// sync until the timeout expires or the stop channel gets closed, and
+	// returns a report for each informer.  If markDegraded is true, the
+	// namespaces that haven't synced by then are marked degraded: informers
+	// report they have synced for the remaining namespaces, while the
+	// degraded ones keep syncing in the background.
+	WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout time.Duration, markDegraded bool) map[reflect.Type]informers.SyncReport
+
+	// WaitForCacheSyncWithPriority blocks until the namespaces with at least
+	// the given priority have synced in all started informers, or the stop
+	// channel gets closed.
+	WaitForCacheSyncWithPriority(stopCh <-chan struct{}, minPriority int) map[reflect.Type]bool
+
+	// SaveSnapshots writes a snapshot of each started informer's caches to a
+	// file in the given directory.
+	SaveSnapshots(dir string) error
+
+	// RestoreSnapshots restores informers from the snapshots in the given
+	// directory written by SaveSnapshots, so they only need to watch for
+	// changes since the snapshots were taken.  It must be called after the
+	// informers have been requested and before the factory is started.
+	// Informers whose snapshot is missing or can't be used list everything
+	// as usual.
+	RestoreSnapshots(dir string) error
+
 	// ForResource gives generic access to a shared informer of the matching type.
 	ForResource(resource schema.GroupVersionResource) (externalversions.GenericInformer, error)
 
@@ -0,0 +1,645 @@
+package informers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// ErrBudgetUnsupported is returned when setting a budget for an informer that
+// wasn't created with a cache.ListerWatcher.
+var ErrBudgetUnsupported = errors.New("informer does not support budgets")
+
+// BudgetConfig limits the objects cached by a multi-namespace informer.  Zero
+// limits are ignored.
+type BudgetConfig struct {
+	// MaxObjectsPerNamespace and MaxBytesPerNamespace limit the objects cached
+	// for each namespace.  They don't apply to metav1.NamespaceAll, whose
+	// objects span every namespace, so SetBudget rejects them for informers
+	// watching it, and they are ignored if it is added later.
+	MaxObjectsPerNamespace int
+	MaxBytesPerNamespace   int64
+
+	// MaxObjects and MaxBytes limit the objects cached for all namespaces
+	// together.  The namespace whose growth exceeds them goes over budget.
+	MaxObjects int
+	MaxBytes   int64
+
+	// SizeFunc estimates the size of an object in bytes.  It defaults to the
+	// length of the object's JSON encoding, and is only used if there are
+	// byte limits.
+	SizeFunc func(obj runtime.Object) int64
+
+	// OnOverBudget is called, if set, when a namespace goes over budget and
+	// its objects are dropped from the cache.
+	OnOverBudget func(namespace string, usage NamespaceUsage)
+
+	// OnWithinBudget is called, if set, when an over budget namespace is back
+	// within budget and is cached again.
+	OnWithinBudget func(namespace string, usage NamespaceUsage)
+}
+
+// NamespaceUsage describes the objects of a single namespace counted against
+// an informer's budget.
+type NamespaceUsage struct {
+	// Objects counts the namespace's objects once for the cache, once more
+	// for each index mirror built by AddIndexers while the informer was
+	// running, and once more if there are delta handlers.  Bytes counts them
+	// once for the cache and once for delta handlers, since mirrors share the
+	// cache's objects.
+	Objects int
+	Bytes   int64
+
+	// OverBudget is true if the namespace's objects aren't being cached.
+	OverBudget bool
+}
+
+// budget keeps track of the number and size of the objects in each namespace,
+// including those of namespaces that are over budget and not cached.  Lists
+// for over budget namespaces come back empty and their watch events are
+// dropped, though both are still counted.  When a namespace goes over or back
+// within budget, its watches fail with an expired error, so the reflector
+// relists and the cache catches up.
+type budget struct {
+	config BudgetConfig
+
+	lock       sync.Mutex
+	namespaces map[string]*namespaceBudget
+
+	// objects and bytes count the objects of namespaces within budget.
+	objects int
+	bytes   int64
+}
+
+// namespaceBudget holds the budget state of a single namespace.
+type namespaceBudget struct {
+	items map[string]int64
+	bytes int64
+	over  bool
+
+	// mirrors is the number of index mirrors holding the namespace's
+	// objects, each counted as another copy of them.  deltas is true if delta
+	// handlers hold their own copy of the objects.
+	mirrors int
+	deltas  bool
+
+	// listing holds the items seen so far by a paginated list, and
+	// listServed records whether that list is returning items.
+	listing    map[string]int64
+	listServed bool
+
+	// relist is true if the last list returned items the namespace's budget
+	// state doesn't agree with, so its next watch must be expired.
+	relist bool
+
+	watches map[*expiringWatch]struct{}
+}
+
+// budgetTransition is a namespace going over or back within budget.
+type budgetTransition struct {
+	namespace string
+	usage     NamespaceUsage
+	watches   []*expiringWatch
+}
+
+func newBudget(config BudgetConfig) *budget {
+	if config.SizeFunc == nil {
+		config.SizeFunc = jsonSize
+	}
+
+	return &budget{
+		config:     config,
+		namespaces: make(map[string]*namespaceBudget),
+	}
+}
+
+// jsonSize returns the length of the object's JSON encoding.
+func jsonSize(obj runtime.Object) int64 {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return 0
+	}
+
+	return int64(len(data))
+}
+
+// size returns the size of the given object, or zero if there are no byte
+// limits.
+func (b *budget) size(obj runtime.Object) int64 {
+	if b.config.MaxBytes <= 0 && b.config.MaxBytesPerNamespace <= 0 {
+		return 0
+	}
+
+	return b.config.SizeFunc(obj)
+}
+
+// state returns the state for the given namespace.  The lock must be held.
+func (b *budget) state(namespace string) *namespaceBudget {
+	s, ok := b.namespaces[namespace]
+	if !ok {
+		s = &namespaceBudget{
+			items:   make(map[string]int64),
+			watches: make(map[*expiringWatch]struct{}),
+		}
+		b.namespaces[namespace] = s
+
+		if namespace == metav1.NamespaceAll && b.hasNamespaceLimits() {
+			klog.Warningf("Per-namespace budgets don't apply to the cluster-wide informer, only the total budget does")
+		}
+	}
+
+	return s
+}
+
+// list counts the objects in a page of a list for the given namespace, and
+// returns the page to hand to the reflector, which is empty if the namespace
+// is over budget.
+func (b *budget) list(namespace string, options metav1.ListOptions, list runtime.Object) (runtime.Object, error) {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	listMeta, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[string]int64, len(items))
+	for _, item := range items {
+		key, err := cache.MetaNamespaceKeyFunc(item)
+		if err != nil {
+			return nil, err
+		}
+
+		sizes[key] = b.size(item)
+	}
+
+	var transitions []budgetTransition
+
+	served := func() bool {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		s := b.state(namespace)
+
+		if options.Continue == "" || s.listing == nil {
+			s.listing = make(map[string]int64, len(sizes))
+			s.listServed = !s.over
+		}
+
+		for key, size := range sizes {
+			s.listing[key] = size
+		}
+
+		served := s.listServed
+
+		if listMeta.GetContinue() == "" {
+			b.setItems(s, s.listing)
+			s.listing = nil
+
+			transitions = b.evaluate(namespace)
+			s.relist = s.over == served
+		}
+
+		return served
+	}()
+
+	b.notify(transitions)
+
+	if served {
+		return list, nil
+	}
+
+	empty := list.DeepCopyObject()
+	if err := meta.SetList(empty, nil); err != nil {
+		return nil, err
+	}
+
+	return empty, nil
+}
+
+// setItems replaces the items of the given namespace.  The lock must be held.
+func (b *budget) setItems(s *namespaceBudget, items map[string]int64) {
+	var bytes int64
+	for _, size := range items {
+		bytes += size
+	}
+
+	if !s.over {
+		b.objects += (len(items) - len(s.items)) * s.copies()
+		b.bytes += (bytes - s.bytes) * s.byteCopies()
+	}
+
+	s.items = items
+	s.bytes = bytes
+}
+
+// setItem records the size of an added or updated object, or removes it if
+// the size is negative.  The lock must be held.
+func (b *budget) setItem(s *namespaceBudget, key string, size int64) {
+	oldSize, existed := s.items[key]
+
+	objects, bytes := 0, -oldSize
+	if existed {
+		objects--
+	}
+
+	if size >= 0 {
+		s.items[key] = size
+		objects++
+		bytes += size
+	} else {
+		delete(s.items, key)
+	}
+
+	s.bytes += bytes
+
+	if !s.over {
+		b.objects += objects * s.copies()
+		b.bytes += bytes * s.byteCopies()
+	}
+}
+
+// hasNamespaceLimits returns true if the budget limits each namespace.
+func (b *budget) hasNamespaceLimits() bool {
+	return b.config.MaxObjectsPerNamespace > 0 || b.config.MaxBytesPerNamespace > 0
+}
+
+// overLimits returns true if the given namespace usage, and the given total
+// usage of all namespaces within budget, exceed any of the limits.  The
+// per-namespace limits don't apply to metav1.NamespaceAll.
+func (b *budget) overLimits(namespace string, s *namespaceBudget, objects int, bytes int64) bool {
+	c := b.config
+
+	if namespace != metav1.NamespaceAll {
+		if (c.MaxObjectsPerNamespace > 0 && s.objects() > c.MaxObjectsPerNamespace) ||
+			(c.MaxBytesPerNamespace > 0 && s.totalBytes() > c.MaxBytesPerNamespace) {
+			return true
+		}
+	}
+
+	return (c.MaxObjects > 0 && objects > c.MaxObjects) ||
+		(c.MaxBytes > 0 && bytes > c.MaxBytes)
+}
+
+// evaluate updates the budget state of the given namespace after its usage
+// changed, then brings back any over budget namespaces that fit again.  It
+// returns the namespaces whose state changed.  The lock must be held.
+func (b *budget) evaluate(namespace string) []budgetTransition {
+	var transitions []budgetTransition
+
+	if s := b.state(namespace); !s.over && b.overLimits(namespace, s, b.objects, b.bytes) {
+		s.over = true
+		b.objects -= s.objects()
+		b.bytes -= s.totalBytes()
+
+		transitions = append(transitions, s.transition(namespace))
+	}
+
+	return append(transitions, b.resume()...)
+}
+
+// resume brings back the over budget namespaces that fit within the budget
+// again, in order of name.  The lock must be held.
+func (b *budget) resume() []budgetTransition {
+	var transitions []budgetTransition
+
+	var over []string
+	for ns, s := range b.namespaces {
+		if s.over {
+			over = append(over, ns)
+		}
+	}
+
+	sort.Strings(over)
+
+	for _, ns := range over {
+		s := b.namespaces[ns]
+
+		objects, bytes := b.objects+s.objects(), b.bytes+s.totalBytes()
+		if b.overLimits(ns, s, objects, bytes) {
+			continue
+		}
+
+		s.over = false
+		b.objects, b.bytes = objects, bytes
+
+		transitions = append(transitions, s.transition(ns))
+	}
+
+	return transitions
+}
+
+// notify calls the callbacks for the given transitions and expires the
+// watches of the namespaces involved, so they relist.
+func (b *budget) notify(transitions []budgetTransition) {
+	for _, t := range transitions {
+		if t.usage.OverBudget {
+			klog.Warningf("Namespace %q is over budget with %d objects (%d bytes), no longer caching it",
+				t.namespace, t.usage.Objects, t.usage.Bytes)
+
+			if b.config.OnOverBudget != nil {
+				b.config.OnOverBudget(t.namespace, t.usage)
+			}
+		} else {
+			klog.Infof("Namespace %q is back within budget with %d objects (%d bytes), caching it again",
+				t.namespace, t.usage.Objects, t.usage.Bytes)
+
+			if b.config.OnWithinBudget != nil {
+				b.config.OnWithinBudget(t.namespace, t.usage)
+			}
+		}
+
+		for _, w := range t.watches {
+			w.expire()
+		}
+	}
+}
+
+// record counts a watch event for the given namespace.  It returns true if the
+// event should be passed on to the reflector.
+func (b *budget) record(namespace string, e watch.Event) bool {
+	var size int64
+
+	switch e.Type {
+	case watch.Added, watch.Modified:
+		size = b.size(e.Object)
+	case watch.Deleted:
+		size = -1
+	default:
+		return true
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(e.Object)
+	if err != nil {
+		return true
+	}
+
+	var transitions []budgetTransition
+
+	forward := func() bool {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		s := b.state(namespace)
+		wasOver := s.over
+
+		b.setItem(s, key, size)
+		transitions = b.evaluate(namespace)
+
+		return !wasOver && !s.over
+	}()
+
+	b.notify(transitions)
+
+	return forward
+}
+
+// track returns a watch.Interface that counts the events of the given watch,
+// and drops them while the namespace is over budget.
+func (b *budget) track(namespace string, w watch.Interface) watch.Interface {
+	bw := newExpiringWatch(w, fmt.Sprintf("budget state of namespace %q changed", namespace), func(e watch.Event) bool {
+		return b.record(namespace, e)
+	}, func(bw *expiringWatch) {
+		b.untrack(namespace, bw)
+	})
+
+	b.lock.Lock()
+	s := b.state(namespace)
+	s.watches[bw] = struct{}{}
+	relist := s.relist
+	s.relist = false
+	b.lock.Unlock()
+
+	if relist {
+		bw.expire()
+	}
+
+	bw.start()
+
+	return bw
+}
+
+func (b *budget) untrack(namespace string, w *expiringWatch) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if s, ok := b.namespaces[namespace]; ok {
+		delete(s.watches, w)
+	}
+}
+
+// remove forgets the given namespace, which may bring other namespaces back
+// within budget.
+func (b *budget) remove(namespace string) {
+	var transitions []budgetTransition
+
+	func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+
+		s, ok := b.namespaces[namespace]
+		if !ok {
+			return
+		}
+
+		if !s.over {
+			b.objects -= s.objects()
+			b.bytes -= s.totalBytes()
+		}
+
+		delete(b.namespaces, namespace)
+
+		transitions = b.resume()
+	}()
+
+	b.notify(transitions)
+}
+
+// setMirrors sets the number of index mirrors holding the given namespace's
+// objects.  The new usage is acted on by check, or by the namespace's next
+// list or event.
+func (b *budget) setMirrors(namespace string, mirrors int) {
+	b.setCopies(namespace, func(s *namespaceBudget) {
+		s.mirrors = mirrors
+	})
+}
+
+// setDeltas sets whether delta handlers hold a copy of the given namespace's
+// objects.  The new usage is acted on by check, or by the namespace's next
+// list or event.
+func (b *budget) setDeltas(namespace string, deltas bool) {
+	b.setCopies(namespace, func(s *namespaceBudget) {
+		s.deltas = deltas
+	})
+}
+
+// setCopies changes the number of copies of the given namespace's objects with
+// the given function, updating the total usage to match.
+func (b *budget) setCopies(namespace string, set func(s *namespaceBudget)) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	s := b.state(namespace)
+
+	if !s.over {
+		b.objects -= s.objects()
+		b.bytes -= s.totalBytes()
+	}
+
+	set(s)
+
+	if !s.over {
+		b.objects += s.objects()
+		b.bytes += s.totalBytes()
+	}
+}
+
+// check updates the budget state of the given namespace after its usage
+// changed outside of a list or event.
+func (b *budget) check(namespace string) {
+	b.lock.Lock()
+	transitions := b.evaluate(namespace)
+	b.lock.Unlock()
+
+	b.notify(transitions)
+}
+
+// usage returns the usage of every namespace.
+func (b *budget) usage() map[string]NamespaceUsage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	res := make(map[string]NamespaceUsage, len(b.namespaces))
+	for namespace, s := range b.namespaces {
+		res[namespace] = s.usage()
+	}
+
+	return res
+}
+
+// transition returns a transition of the namespace to its current state.
+func (s *namespaceBudget) transition(namespace string) budgetTransition {
+	t := budgetTransition{namespace: namespace, usage: s.usage()}
+	for w := range s.watches {
+		t.watches = append(t.watches, w)
+	}
+
+	return t
+}
+
+// copies returns the number of times each of the namespace's objects is held.
+func (s *namespaceBudget) copies() int {
+	return int(s.byteCopies()) + s.mirrors
+}
+
+// byteCopies returns the number of copies of the namespace's objects that
+// don't share their data.
+func (s *namespaceBudget) byteCopies() int64 {
+	if s.deltas {
+		return 2
+	}
+
+	return 1
+}
+
+// objects returns the number of objects counted for the namespace.
+func (s *namespaceBudget) objects() int {
+	return len(s.items) * s.copies()
+}
+
+// totalBytes returns the size of the objects counted for the namespace.
+func (s *namespaceBudget) totalBytes() int64 {
+	return s.bytes * s.byteCopies()
+}
+
+func (s *namespaceBudget) usage() NamespaceUsage {
+	return NamespaceUsage{
+		Objects:    s.objects(),
+		Bytes:      s.totalBytes(),
+		OverBudget: s.over,
+	}
+}
+
+// SetBudget limits the number and size of the objects the informer caches.
+// When a namespace goes over budget, its objects are dropped from the cache,
+// which event handlers see as deletions, and OnOverBudget is called.  The
+// namespace is still listed and watched to keep count of its objects, and is
+// cached again once it fits within the budget.  Per-namespace limits can't be
+// set for informers watching metav1.NamespaceAll.  This is only supported by
+// informers created with a cache.ListerWatcher, and can only be done before
+// the informer is started.
+func (i *multiNamespaceInformer) SetBudget(config BudgetConfig) error {
+	if i.newListerWatcher == nil {
+		return ErrBudgetUnsupported
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.started {
+		return fmt.Errorf("informer has already started")
+	}
+
+	if i.budget != nil {
+		return fmt.Errorf("budget already set")
+	}
+
+	b := newBudget(config)
+
+	if b.hasNamespaceLimits() && i.namespaces.Contains(metav1.NamespaceAll) {
+		return fmt.Errorf("per-namespace budgets can't be applied to a cluster-wide informer")
+	}
+
+	i.budget = b
+
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for namespace := range i.informers {
+		i.countDeltas(namespace)
+	}
+
+	return nil
+}
+
+// BudgetUsage returns the usage of each namespace counted against the
+// informer's budget, or nil if no budget is set.
+func (i *multiNamespaceInformer) BudgetUsage() map[string]NamespaceUsage {
+	if b := i.getBudget(); b != nil {
+		return b.usage()
+	}
+
+	return nil
+}
+
+// OverBudgetNamespaces returns the sorted namespaces that are over budget.
+func (i *multiNamespaceInformer) OverBudgetNamespaces() []string {
+	var res []string
+	for namespace, usage := range i.BudgetUsage() {
+		if usage.OverBudget {
+			res = append(res, namespace)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+func (i *multiNamespaceInformer) getBudget() *budget {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.budget
+}
@@ -0,0 +1,264 @@
+package informers_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// waitForNoKeys waits until none of the given keys are in the informer's cache.
+func waitForNoKeys(t *testing.T, informer cache.SharedIndexInformer, keys ...string) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		for _, key := range keys {
+			if _, exists, err := informer.GetIndexer().GetByKey(key); err != nil || exists {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for keys %v to be removed: %v", keys, err)
+	}
+}
+
+func TestMultiNamespaceInformerBudget(t *testing.T) {
+	noisy := fcache.NewFakeControllerSource()
+	noisy.Add(newPod("ns1", "pod1"))
+
+	quiet := fcache.NewFakeControllerSource()
+	quiet.Add(newPod("ns2", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": noisy,
+		"ns2": quiet,
+	})
+
+	var (
+		lock   sync.Mutex
+		over   []string
+		within []string
+	)
+
+	err := informer.SetBudget(xnsinformers.BudgetConfig{
+		MaxObjectsPerNamespace: 2,
+		OnOverBudget: func(namespace string, _ xnsinformers.NamespaceUsage) {
+			lock.Lock()
+			defer lock.Unlock()
+			over = append(over, namespace)
+		},
+		OnWithinBudget: func(namespace string, _ xnsinformers.NamespaceUsage) {
+			lock.Lock()
+			defer lock.Unlock()
+			within = append(within, namespace)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	waitForKeys(t, informer, "ns1/pod1", "ns2/pod1")
+
+	noisy.Add(newPod("ns1", "pod2"))
+	noisy.Add(newPod("ns1", "pod3"))
+
+	// ns1 goes over budget and is dropped from the cache, but ns2 isn't.
+	waitForNoKeys(t, informer, "ns1/pod1", "ns1/pod2", "ns1/pod3")
+	waitForKeys(t, informer, "ns2/pod1")
+
+	if namespaces := informer.OverBudgetNamespaces(); !reflect.DeepEqual(namespaces, []string{"ns1"}) {
+		t.Errorf("Expected ns1 to be over budget, got: %v", namespaces)
+	}
+
+	usage := informer.BudgetUsage()["ns1"]
+	if expected := (xnsinformers.NamespaceUsage{Objects: 3, OverBudget: true}); usage != expected {
+		t.Errorf("\n- got: %+v\n- want: %+v", usage, expected)
+	}
+
+	// ns1 is still counted, and is cached again once it fits.
+	noisy.Delete(newPod("ns1", "pod3"))
+
+	waitForKeys(t, informer, "ns1/pod1", "ns1/pod2")
+
+	if namespaces := informer.OverBudgetNamespaces(); len(namespaces) != 0 {
+		t.Errorf("Expected no namespaces over budget, got: %v", namespaces)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !reflect.DeepEqual(over, []string{"ns1"}) || !reflect.DeepEqual(within, []string{"ns1"}) {
+		t.Errorf("Unexpected budget callbacks, over: %v, within: %v", over, within)
+	}
+}
+
+func TestMultiNamespaceInformerTotalBudget(t *testing.T) {
+	source1 := fcache.NewFakeControllerSource()
+	source1.Add(newPod("ns1", "pod1"))
+
+	source2 := fcache.NewFakeControllerSource()
+	source2.Add(newPod("ns2", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": source1,
+		"ns2": source2,
+	})
+
+	if err := informer.SetBudget(xnsinformers.BudgetConfig{MaxObjects: 3}); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	waitForKeys(t, informer, "ns1/pod1", "ns2/pod1")
+
+	source2.Add(newPod("ns2", "pod2"))
+	source2.Add(newPod("ns2", "pod3"))
+
+	// ns2 pushed the total over budget, so it is the one dropped.
+	waitForNoKeys(t, informer, "ns2/pod1", "ns2/pod2", "ns2/pod3")
+	waitForKeys(t, informer, "ns1/pod1")
+
+	// Removing ns1 makes room for ns2 again.
+	informer.RemoveNamespace("ns1")
+
+	waitForKeys(t, informer, "ns2/pod1", "ns2/pod2", "ns2/pod3")
+}
+
+func TestMultiNamespaceInformerBudgetUnsupported(t *testing.T) {
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": fcache.NewFakeControllerSource(),
+	})
+
+	if err := informer.SetBudget(xnsinformers.BudgetConfig{MaxObjects: 1}); !errors.Is(err, xnsinformers.ErrBudgetUnsupported) {
+		t.Errorf("Expected unsupported error, got: %v", err)
+	}
+}
+
+func TestMultiNamespaceInformerBudgetNamespaceAll(t *testing.T) {
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		metav1.NamespaceAll: fcache.NewFakeControllerSource(),
+	})
+
+	err := informer.SetBudget(xnsinformers.BudgetConfig{MaxObjectsPerNamespace: 2})
+	if err == nil {
+		t.Errorf("Expected an error setting a per-namespace budget for a cluster-wide informer")
+	}
+
+	if err := informer.SetBudget(xnsinformers.BudgetConfig{MaxObjects: 2}); err != nil {
+		t.Errorf("Failed to set a total budget for a cluster-wide informer: %v", err)
+	}
+}
+
+func TestMultiNamespaceInformerBudgetIndexMirrors(t *testing.T) {
+	source1 := fcache.NewFakeControllerSource()
+	source1.Add(newPod("ns1", "pod1"))
+	source1.Add(newPod("ns1", "pod2"))
+
+	source2 := fcache.NewFakeControllerSource()
+	source2.Add(newPod("ns2", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": source1,
+		"ns2": source2,
+	})
+
+	if err := informer.SetBudget(xnsinformers.BudgetConfig{MaxObjectsPerNamespace: 3}); err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	waitForKeys(t, informer, "ns1/pod1", "ns1/pod2", "ns2/pod1")
+
+	// The mirror built for the running informer counts as another copy of
+	// each object, which takes ns1 over budget.
+	err := informer.AddIndexers(cache.Indexers{
+		"byName": func(obj interface{}) ([]string, error) {
+			return []string{obj.(*v1.Pod).Name}, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add indexers: %v", err)
+	}
+
+	if namespaces := informer.OverBudgetNamespaces(); !reflect.DeepEqual(namespaces, []string{"ns1"}) {
+		t.Errorf("Expected ns1 to be over budget, got: %v", namespaces)
+	}
+
+	usage := informer.BudgetUsage()["ns2"]
+	if expected := (xnsinformers.NamespaceUsage{Objects: 2}); usage != expected {
+		t.Errorf("\n- got: %+v\n- want: %+v", usage, expected)
+	}
+
+	waitForNoKeys(t, informer, "ns1/pod1", "ns1/pod2")
+}
+
+func TestMultiNamespaceInformerBudgetDeltaHandlers(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	err := informer.SetBudget(xnsinformers.BudgetConfig{
+		MaxObjectsPerNamespace: 3,
+		MaxBytesPerNamespace:   1000,
+		SizeFunc:               func(runtime.Object) int64 { return 10 },
+	})
+	if err != nil {
+		t.Fatalf("Failed to set budget: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	// Delta handlers share a copy of the namespace's objects, which is
+	// counted once however many handlers there are.
+	for n := 0; n < 2; n++ {
+		if _, err := informer.AddDeltaHandler(&deltaRecorder{}, 0); err != nil {
+			t.Fatalf("Failed to add delta handler: %v", err)
+		}
+	}
+
+	usage := informer.BudgetUsage()["ns1"]
+	if expected := (xnsinformers.NamespaceUsage{Objects: 2, Bytes: 20}); usage != expected {
+		t.Errorf("\n- got: %+v\n- want: %+v", usage, expected)
+	}
+
+	source.Add(newPod("ns1", "pod2"))
+
+	waitForNoKeys(t, informer, "ns1/pod1", "ns1/pod2")
+
+	if namespaces := informer.OverBudgetNamespaces(); !reflect.DeepEqual(namespaces, []string{"ns1"}) {
+		t.Errorf("Expected ns1 to be over budget, got: %v", namespaces)
+	}
+}
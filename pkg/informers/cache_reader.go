@@ -61,6 +61,10 @@ func (c *cacheReader) ListKeys() (res []string) {
 
 func (c *cacheReader) GetIndexers() cache.Indexers {
 	res := cache.Indexers{}
+	if g, ok := c.indexer.(globalIndexer); ok {
+		res = g.globalIndexers()
+	}
+
 	for _, idx := range c.indexer.GetIndexers() {
 		for k, v := range idx.GetIndexers() {
 			res[k] = v
@@ -71,6 +75,10 @@ func (c *cacheReader) GetIndexers() cache.Indexers {
 }
 
 func (c *cacheReader) Index(indexName string, obj interface{}) (res []interface{}, err error) {
+	if idx := c.globalIndex(indexName); idx != nil {
+		return idx.Index(indexName, obj)
+	}
+
 	for _, idx := range c.indexer.GetIndexers() {
 		objs, err := idx.Index(indexName, obj)
 		if err != nil {
@@ -84,6 +92,10 @@ func (c *cacheReader) Index(indexName string, obj interface{}) (res []interface{
 }
 
 func (c *cacheReader) IndexKeys(indexName, indexKey string) (res []string, err error) {
+	if idx := c.globalIndex(indexName); idx != nil {
+		return idx.IndexKeys(indexName, indexKey)
+	}
+
 	for _, idx := range c.indexer.GetIndexers() {
 		keys, err := idx.IndexKeys(indexName, indexKey)
 		if err != nil {
@@ -97,6 +109,10 @@ func (c *cacheReader) IndexKeys(indexName, indexKey string) (res []string, err e
 }
 
 func (c *cacheReader) ListIndexFuncValues(indexName string) (res []string) {
+	if idx := c.globalIndex(indexName); idx != nil {
+		return idx.ListIndexFuncValues(indexName)
+	}
+
 	for _, idx := range c.indexer.GetIndexers() {
 		res = append(res, idx.ListIndexFuncValues(indexName)...)
 	}
@@ -105,6 +121,10 @@ func (c *cacheReader) ListIndexFuncValues(indexName string) (res []string) {
 }
 
 func (c *cacheReader) ByIndex(indexName, indexKey string) (res []interface{}, err error) {
+	if idx := c.globalIndex(indexName); idx != nil {
+		return idx.ByIndex(indexName, indexKey)
+	}
+
 	for _, idx := range c.indexer.GetIndexers() {
 		keys, err := idx.ByIndex(indexName, indexKey)
 		if err != nil {
@@ -169,3 +189,13 @@ func (c *cacheReader) indexerForNamespace(namespace string) (cache.Indexer, bool
 
 	return nil, false
 }
+
+// globalIndex returns the global indexer holding the given index, or nil if the
+// underlying indexer has no such global index.
+func (c *cacheReader) globalIndex(indexName string) cache.Indexer {
+	if g, ok := c.indexer.(globalIndexer); ok {
+		return g.globalIndex(indexName)
+	}
+
+	return nil
+}
@@ -0,0 +1,304 @@
+package informers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// snapshotStore is a copy of the objects in every namespace's cache, kept up to
+// date by feeds from the informers.  All namespaces share a single lock, so a
+// snapshot sees every namespace at the same point.  Each namespace's objects
+// are kept in their own map, which is copied on the first write after it was
+// handed out in a snapshot.  Taking a snapshot therefore only copies the map of
+// namespaces.
+type snapshotStore struct {
+	lock       sync.Mutex
+	namespaces map[string]*snapshotNamespace
+}
+
+// snapshotNamespace holds the objects for a single namespace, keyed like the
+// informer's cache.  The items map must not be modified while shared is true.
+type snapshotNamespace struct {
+	items  map[string]interface{}
+	shared bool
+}
+
+func newSnapshotStore() *snapshotStore {
+	return &snapshotStore{namespaces: make(map[string]*snapshotNamespace)}
+}
+
+// namespaceHandler returns an event handler that keeps the given namespace's
+// objects up to date.
+func (s *snapshotStore) namespaceHandler(namespace string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			s.set(namespace, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			s.set(namespace, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			s.remove(namespace, obj)
+		},
+	}
+}
+
+func (s *snapshotStore) set(namespace string, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.writable(namespace)[key] = obj
+}
+
+func (s *snapshotStore) remove(namespace string, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.writable(namespace), key)
+}
+
+// writable returns the given namespace's items, copying them first if they are
+// shared with a snapshot.  The lock must be held.
+func (s *snapshotStore) writable(namespace string) map[string]interface{} {
+	ns, ok := s.namespaces[namespace]
+	if !ok {
+		ns = &snapshotNamespace{items: make(map[string]interface{})}
+		s.namespaces[namespace] = ns
+	}
+
+	if ns.shared {
+		items := make(map[string]interface{}, len(ns.items))
+		for k, v := range ns.items {
+			items[k] = v
+		}
+
+		ns.items = items
+		ns.shared = false
+	}
+
+	return ns.items
+}
+
+// removeNamespace removes all objects for the given namespace.
+func (s *snapshotStore) removeNamespace(namespace string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.namespaces, namespace)
+}
+
+// snapshot returns the current items of every namespace.  The returned maps
+// must not be modified.
+func (s *snapshotStore) snapshot() map[string]map[string]interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make(map[string]map[string]interface{}, len(s.namespaces))
+	for namespace, ns := range s.namespaces {
+		ns.shared = true
+		res[namespace] = ns.items
+	}
+
+	return res
+}
+
+// Snapshot returns a read-only cache.Indexer holding the contents of every
+// namespace's cache at a single point in time.  Unlike the indexer returned by
+// GetIndexer, it never shows one namespace before and another after a change
+// made while it is being read.  Indexes are built the first time the snapshot
+// is queried by index.
+//
+// The first call starts keeping a copy of the informer's objects for snapshots
+// and waits until it has caught up with the cache of every namespace that has
+// synced.  Later calls only cost a copy of the map of namespaces, since each
+// namespace's objects are copied on write.
+//
+// The wait ends early if the informer is stopped, in which case the snapshot
+// may miss objects of the namespaces that hadn't caught up.
+func (i *multiNamespaceInformer) Snapshot() cache.Indexer {
+	store, feeds, runStopCh := i.snapshotStore()
+
+	if len(feeds) > 0 {
+		ctx := wait.ContextForChannel(runStopCh)
+		_ = wait.PollUntilContextCancel(ctx, 10*time.Millisecond, true, func(ctx context.Context) (bool, error) {
+			for _, f := range feeds {
+				if !f.hasSynced() {
+					return false, nil
+				}
+			}
+			return true, nil
+		})
+	}
+
+	i.lock.Lock()
+	indexers := i.allIndexers()
+	i.lock.Unlock()
+
+	return &cacheSnapshot{
+		namespaces: store.snapshot(),
+		indexers:   indexers,
+	}
+}
+
+// snapshotStore returns the informer's snapshot store, creating it if needed.
+// If the store was created while the informer is running, the feeds for the
+// namespaces that had already synced are returned as well, along with the
+// channel closed when the run ends.
+func (i *multiNamespaceInformer) snapshotStore() (*snapshotStore, []*namespaceFeed, <-chan struct{}) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.snapshots != nil {
+		return i.snapshots, nil, nil
+	}
+
+	i.snapshots = newSnapshotStore()
+
+	var synced []*namespaceFeed
+	for namespace, informer := range i.informers {
+		f, err := i.addFeed(namespace, informer, i.snapshots.namespaceHandler(namespace))
+		if err != nil {
+			klog.Errorf("Failed to track namespace %q for snapshots: %v", namespace, err)
+			continue
+		}
+
+		if i.isRunning() && informer.HasSynced() {
+			synced = append(synced, f)
+		}
+	}
+
+	return i.snapshots, synced, i.runStopCh
+}
+
+// cacheSnapshot is a read-only cache.Indexer over a point-in-time copy of the
+// objects in every namespace.
+type cacheSnapshot struct {
+	namespaces map[string]map[string]interface{}
+	indexers   cache.Indexers
+
+	indexOnce sync.Once
+	indexer   cache.Indexer
+}
+
+var _ cache.Indexer = &cacheSnapshot{}
+
+func (c *cacheSnapshot) Add(obj interface{}) error {
+	return ErrCacheReadOnly
+}
+
+func (c *cacheSnapshot) Update(obj interface{}) error {
+	return ErrCacheReadOnly
+}
+
+func (c *cacheSnapshot) Delete(obj interface{}) error {
+	return ErrCacheReadOnly
+}
+
+func (c *cacheSnapshot) List() (res []interface{}) {
+	for _, items := range c.namespaces {
+		for _, obj := range items {
+			res = append(res, obj)
+		}
+	}
+
+	return res
+}
+
+func (c *cacheSnapshot) ListKeys() (res []string) {
+	for _, items := range c.namespaces {
+		for key := range items {
+			res = append(res, key)
+		}
+	}
+
+	return res
+}
+
+func (c *cacheSnapshot) Get(obj interface{}) (item interface{}, exists bool, err error) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return c.GetByKey(key)
+}
+
+func (c *cacheSnapshot) GetByKey(key string) (item interface{}, exists bool, err error) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	item, exists = c.itemsForNamespace(namespace)[key]
+
+	return item, exists, nil
+}
+
+// itemsForNamespace returns the items holding the given namespace's objects,
+// which are those of metav1.NamespaceAll if the snapshot has them.
+func (c *cacheSnapshot) itemsForNamespace(namespace string) map[string]interface{} {
+	if items, ok := c.namespaces[metav1.NamespaceAll]; ok {
+		return items
+	}
+
+	return c.namespaces[namespace]
+}
+
+func (c *cacheSnapshot) Replace(list []interface{}, resourceVersion string) error {
+	return ErrCacheReadOnly
+}
+
+func (c *cacheSnapshot) Resync() error {
+	return nil
+}
+
+func (c *cacheSnapshot) GetIndexers() cache.Indexers {
+	return c.indexers
+}
+
+func (c *cacheSnapshot) AddIndexers(newIndexers cache.Indexers) error {
+	return ErrCacheReadOnly
+}
+
+func (c *cacheSnapshot) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	return c.index().Index(indexName, obj)
+}
+
+func (c *cacheSnapshot) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	return c.index().IndexKeys(indexName, indexedValue)
+}
+
+func (c *cacheSnapshot) ListIndexFuncValues(indexName string) []string {
+	return c.index().ListIndexFuncValues(indexName)
+}
+
+func (c *cacheSnapshot) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	return c.index().ByIndex(indexName, indexedValue)
+}
+
+// index returns an indexer over the snapshot's objects, building it on first
+// use.
+func (c *cacheSnapshot) index() cache.Indexer {
+	c.indexOnce.Do(func() {
+		c.indexer = cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, c.indexers)
+		_ = c.indexer.Replace(c.List(), "")
+	})
+
+	return c.indexer
+}
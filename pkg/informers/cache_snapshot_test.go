@@ -0,0 +1,100 @@
+package informers_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiNamespaceInformerSnapshot(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+	}
+
+	sources["ns1"].Add(newPodOnNode("ns1", "pod1", "node1"))
+	sources["ns2"].Add(newPodOnNode("ns2", "pod2", "node1"))
+
+	namespaceSet := xnsinformers.NewNamespaceSet("ns1", "ns2")
+	informer := xnsinformers.NewMultiNamespaceInformer(namespaceSet, 0, func(namespace string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(sources[namespace], &v1.Pod{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+
+	if err := informer.AddGlobalIndexers(cache.Indexers{byNodeIndex: indexByNode}); err != nil {
+		t.Fatalf("Failed to add global indexers: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	snapshot := informer.Snapshot()
+
+	keys := snapshot.ListKeys()
+	sort.Strings(keys)
+
+	if expected := []string{"ns1/pod1", "ns2/pod2"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("Expected keys %v, got %v", expected, keys)
+	}
+
+	// Changes after the snapshot was taken aren't seen by it.
+	sources["ns1"].Delete(newPodOnNode("ns1", "pod1", "node1"))
+	sources["ns2"].Add(newPodOnNode("ns2", "pod3", "node1"))
+	waitForKeys(t, informer, "ns2/pod3")
+	waitForIndex(t, informer.Snapshot(), byNodeIndex, "node1", "ns2/pod2", "ns2/pod3")
+
+	if _, exists, _ := snapshot.GetByKey("ns1/pod1"); !exists {
+		t.Errorf("Expected ns1/pod1 to still be in the snapshot")
+	}
+
+	if _, exists, _ := snapshot.GetByKey("ns2/pod3"); exists {
+		t.Errorf("Expected ns2/pod3 not to be in the snapshot")
+	}
+
+	// Both per-namespace and global indexes are available.
+	waitForIndex(t, snapshot, byNodeIndex, "node1", "ns1/pod1", "ns2/pod2")
+	waitForIndex(t, snapshot, cache.NamespaceIndex, "ns1", "ns1/pod1")
+
+	if err := snapshot.Add(newPod("ns1", "pod4")); err != xnsinformers.ErrCacheReadOnly {
+		t.Errorf("Expected read-only error, got: %v", err)
+	}
+}
+
+func TestMultiNamespaceInformerSnapshotNamespaceAll(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+	source.Add(newPod("ns2", "pod2"))
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{metav1.NamespaceAll: source})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	snapshot := informer.Snapshot()
+
+	for _, key := range []string{"ns1/pod1", "ns2/pod2"} {
+		if _, exists, err := snapshot.GetByKey(key); err != nil || !exists {
+			t.Errorf("Expected %s in the snapshot: %v", key, err)
+		}
+	}
+
+	if _, exists, err := snapshot.Get(newPod("ns1", "pod1")); err != nil || !exists {
+		t.Errorf("Expected ns1/pod1 in the snapshot: %v", err)
+	}
+
+	if _, exists, _ := snapshot.GetByKey("ns3/pod3"); exists {
+		t.Errorf("Expected ns3/pod3 not to be in the snapshot")
+	}
+}
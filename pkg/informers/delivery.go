@@ -0,0 +1,246 @@
+package informers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// HandlerOption configures how events are delivered to an event handler added
+// with AddEventHandlerWithOptions.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	coalesceWindow time.Duration
+
+	// qps and burst limit delivery to the handler across all namespaces, and
+	// namespaceQPS and namespaceBurst limit delivery per namespace.  Zero
+	// values mean no limit.
+	qps            float32
+	burst          int
+	namespaceQPS   float32
+	namespaceBurst int
+
+	// workers and queueSize configure the handler's worker pool, if any.
+	workers   int
+	queueSize int
+}
+
+// WithCoalescing holds events for up to the given window before delivering
+// them.  Events for the same object within the window are collapsed, so many
+// updates become a single OnUpdate call with the first old object and the last
+// new one.
+func WithCoalescing(window time.Duration) HandlerOption {
+	return func(o *handlerOptions) {
+		o.coalesceWindow = window
+	}
+}
+
+// WithRateLimit limits the rate at which events are delivered to the handler
+// across all namespaces.  Events held back by the limit are coalesced.
+func WithRateLimit(qps float32, burst int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.qps = qps
+		o.burst = burst
+	}
+}
+
+// WithNamespaceRateLimit limits the rate at which events for each namespace
+// are delivered to the handler.  Events held back by the limit are coalesced.
+func WithNamespaceRateLimit(qps float32, burst int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.namespaceQPS = qps
+		o.namespaceBurst = burst
+	}
+}
+
+// throttled returns true if events for the handler need to go through a
+// throttledHandler.
+func (o *handlerOptions) throttled() bool {
+	return o.coalesceWindow > 0 || o.qps > 0 || o.namespaceQPS > 0
+}
+
+// newLimiter returns the rate limiter shared by all namespaces, if any.
+func (o *handlerOptions) newLimiter() flowcontrol.RateLimiter {
+	if o.qps <= 0 {
+		return nil
+	}
+
+	return flowcontrol.NewTokenBucketRateLimiter(o.qps, o.burst)
+}
+
+// newNamespaceLimiter returns a rate limiter for a single namespace, if any.
+func (o *handlerOptions) newNamespaceLimiter() flowcontrol.RateLimiter {
+	if o.namespaceQPS <= 0 {
+		return nil
+	}
+
+	return flowcontrol.NewTokenBucketRateLimiter(o.namespaceQPS, o.namespaceBurst)
+}
+
+// throttledHandler coalesces and rate limits the events for a single namespace
+// before delivering them to the wrapped handler.  Events are queued by key and
+// delivered in the order they were first queued by a goroutine that runs while
+// there are events pending, which is tracked by the given wait group.  Objects
+// in an informer's initial list are delivered right away, so registrations
+// still report when handlers are synced.
+type throttledHandler struct {
+	handler  cache.ResourceEventHandler
+	window   time.Duration
+	limiters []flowcontrol.RateLimiter
+	wg       *sync.WaitGroup
+
+	lock      sync.Mutex
+	pending   *eventQueue
+	scheduled bool
+	closed    bool
+
+	// ctx is cancelled by close, so the flushing goroutine stops waiting on
+	// the rate limiters.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// deliverLock serializes deliveries between the flushing goroutine and
+	// close.
+	deliverLock sync.Mutex
+}
+
+var _ cache.ResourceEventHandler = &throttledHandler{}
+
+func newThrottledHandler(handler cache.ResourceEventHandler, window time.Duration, wg *sync.WaitGroup,
+	limiters ...flowcontrol.RateLimiter,
+) *throttledHandler {
+	h := &throttledHandler{
+		handler: handler,
+		window:  window,
+		wg:      wg,
+		pending: newEventQueue(),
+	}
+
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+
+	for _, l := range limiters {
+		if l != nil {
+			h.limiters = append(h.limiters, l)
+		}
+	}
+
+	return h
+}
+
+func (h *throttledHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.handle(newAddEvent(obj, isInInitialList))
+}
+
+func (h *throttledHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.handle(newUpdateEvent(oldObj, newObj))
+}
+
+func (h *throttledHandler) OnDelete(obj interface{}) {
+	h.handle(newDeleteEvent(obj))
+}
+
+func (h *throttledHandler) handle(e event) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	if _, queued := h.pending.events[e.key]; e.isInInitialList && !queued {
+		h.deliverLock.Lock()
+		defer h.deliverLock.Unlock()
+
+		e.deliver(h.handler)
+		return
+	}
+
+	h.pending.push(e)
+
+	if !h.scheduled {
+		h.scheduled = true
+		h.wg.Add(1)
+
+		if h.window > 0 {
+			time.AfterFunc(h.window, h.flush)
+		} else {
+			go h.flush()
+		}
+	}
+}
+
+// flush delivers pending events until there are none left, waiting on the rate
+// limiters before each one.  Events keep being coalesced while it waits, and
+// it stops waiting once the handler is closed.
+func (h *throttledHandler) flush() {
+	defer h.wg.Done()
+
+	for {
+		if !h.hasPending() {
+			return
+		}
+
+		for _, l := range h.limiters {
+			if err := l.Wait(h.ctx); err != nil {
+				h.lock.Lock()
+				h.scheduled = false
+				h.lock.Unlock()
+				return
+			}
+		}
+
+		h.lock.Lock()
+		e, ok := h.pending.pop()
+		if !ok || h.closed {
+			h.scheduled = false
+			h.lock.Unlock()
+			return
+		}
+
+		h.deliverLock.Lock()
+		h.lock.Unlock()
+
+		e.deliver(h.handler)
+		h.deliverLock.Unlock()
+	}
+}
+
+// hasPending returns true if there are events to deliver.  Otherwise, the
+// flushing goroutine is no longer scheduled.
+func (h *throttledHandler) hasPending() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.closed || len(h.pending.events) == 0 {
+		h.scheduled = false
+		return false
+	}
+
+	return true
+}
+
+// close stops delivery.  If deliver is true, pending events are delivered
+// first, ignoring the rate limits; otherwise they are dropped.
+func (h *throttledHandler) close(deliver bool) {
+	h.lock.Lock()
+	h.closed = true
+	events := h.pending.drain()
+	h.lock.Unlock()
+
+	h.cancel()
+
+	if !deliver {
+		return
+	}
+
+	h.deliverLock.Lock()
+	defer h.deliverLock.Unlock()
+
+	for _, e := range events {
+		e.deliver(h.handler)
+	}
+}
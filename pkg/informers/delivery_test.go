@@ -0,0 +1,191 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiNamespaceInformerCoalescing(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	recorder := &eventRecorder{}
+	registration, err := informer.AddEventHandlerWithOptions(recorder, 0, xnsinformers.WithCoalescing(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	// The initial list isn't held back.
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"add:pod1:1"}) {
+		t.Fatalf("Unexpected initial events: %v", events)
+	}
+
+	for n := 0; n < 5; n++ {
+		source.Modify(newPod("ns1", "pod1"))
+	}
+
+	source.Add(newPod("ns1", "pod2"))
+	source.Delete(newPod("ns1", "pod2"))
+
+	recorder.waitFor(t, 1)
+
+	// Give any further events a chance to show up.
+	time.Sleep(300 * time.Millisecond)
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"update:pod1:1->6"}) {
+		t.Errorf("Unexpected coalesced events: %v", events)
+	}
+}
+
+func TestMultiNamespaceInformerCoalescingWait(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	recorder := &eventRecorder{}
+	registration, err := informer.AddEventHandlerWithOptions(recorder, 0, xnsinformers.WithCoalescing(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+	recorder.reset()
+
+	source.Modify(newPod("ns1", "pod1"))
+
+	err = wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		obj, _, _ := informer.GetStore().GetByKey("ns1/pod1")
+		return obj != nil && obj.(*v1.Pod).ResourceVersion == "2", nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for the update")
+	}
+
+	// The update is still held back when the informer is stopped, and Wait
+	// covers delivering it.
+	informer.Stop()
+	informer.Wait()
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"update:pod1:1->2"}) {
+		t.Errorf("Expected the update to be delivered once Wait returns, got: %v", events)
+	}
+}
+
+func TestMultiNamespaceInformerRateLimitRemoveHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	recorder := &eventRecorder{}
+	registration, err := informer.AddEventHandlerWithOptions(recorder, 0, xnsinformers.WithRateLimit(0.1, 1))
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	// The first pod takes the only token, so the second is held back for
+	// ten seconds.
+	source.Add(newPod("ns1", "pod1"))
+	source.Add(newPod("ns1", "pod2"))
+	waitForKeys(t, informer, "ns1/pod1", "ns1/pod2")
+
+	// Removing the handler drops the held back event, and the goroutine
+	// waiting on the rate limiter stops with it.
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		t.Fatalf("Failed to remove event handler: %v", err)
+	}
+
+	start := time.Now()
+	informer.Stop()
+	informer.Wait()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Wait to return once the handler was removed, took %v", elapsed)
+	}
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"add:pod1:1"}) {
+		t.Errorf("Unexpected events: %v", events)
+	}
+}
+
+func TestMultiNamespaceInformerRateLimit(t *testing.T) {
+	sources := map[string]cache.ListerWatcher{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+	}
+
+	informer := newInformer(&v1.Pod{}, sources)
+
+	recorder := &eventRecorder{}
+	_, _ = informer.AddEventHandlerWithOptions(recorder, 0,
+		xnsinformers.WithRateLimit(10, 1),
+		xnsinformers.WithNamespaceRateLimit(5, 1),
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	for _, ns := range []string{"ns1", "ns2"} {
+		source := sources[ns].(*fcache.FakeControllerSource)
+		source.Add(newPod(ns, "pod1"))
+
+		for n := 0; n < 20; n++ {
+			source.Modify(newPod(ns, "pod1"))
+		}
+	}
+
+	// Updates held back by the limits are coalesced, so each namespace
+	// delivers far fewer events than it received, ending with the latest.
+	var events []string
+	err := wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+
+		events = append([]string(nil), recorder.events...)
+
+		latest := 0
+		for _, e := range events {
+			if strings.HasSuffix(e, ":21") || strings.HasSuffix(e, "->21") {
+				latest++
+			}
+		}
+
+		return latest == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Latest versions not delivered: %v", events)
+	}
+
+	if len(events) > 12 {
+		t.Errorf("Expected a few coalesced events, got %d: %v", len(events), events)
+	}
+}
@@ -0,0 +1,502 @@
+package informers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// ErrDeltaHandlerUnsupported is returned when adding a delta handler to an
+// informer that wasn't created with a cache.ListerWatcher.
+var ErrDeltaHandlerUnsupported = errors.New("informer does not support delta handlers")
+
+// DeltaHandler handles batches of raw deltas for the objects in a namespace.
+type DeltaHandler interface {
+	OnDeltas(namespace string, deltas cache.Deltas)
+}
+
+// DeltaHandlerFunc is an adapter that lets a function be used as a
+// DeltaHandler.
+type DeltaHandlerFunc func(namespace string, deltas cache.Deltas)
+
+// OnDeltas calls f(namespace, deltas).
+func (f DeltaHandlerFunc) OnDeltas(namespace string, deltas cache.Deltas) {
+	f(namespace, deltas)
+}
+
+// deltaHandlerData holds a delta handler and its feed for each namespace.  It
+// is handed out as the cache.ResourceEventHandlerRegistration for the handler.
+type deltaHandlerData struct {
+	informer     *multiNamespaceInformer
+	handler      DeltaHandler
+	resyncPeriod time.Duration
+
+	// feeds is guarded by the informer's deltaLock.
+	feeds map[string]*deltaFeed
+}
+
+var _ cache.ResourceEventHandlerRegistration = &deltaHandlerData{}
+
+// HasSynced reports whether the handler has been sent the initial list of
+// every namespace.
+func (d *deltaHandlerData) HasSynced() bool {
+	if !d.informer.namespaces.Initialized() {
+		return false
+	}
+
+	d.informer.deltaLock.Lock()
+	defer d.informer.deltaLock.Unlock()
+
+	for _, f := range d.feeds {
+		if !f.fifo.HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// deltaFeed rebuilds the deltas of a single namespace for a delta handler from
+// the lists and watch events seen by the namespace's cache.ListerWatcher, and
+// delivers them from its own goroutine.  Its objects are copies of those seen
+// by the informer, shared with the other feeds of the namespace.
+type deltaFeed struct {
+	namespace string
+	handler   DeltaHandler
+	fifo      *cache.DeltaFIFO
+
+	// known holds the objects the handler has been told about, so a list
+	// produces deletions for objects that are gone.  It is handed on to the
+	// feed replacing this one when the informer is restarted.
+	known cache.Store
+
+	// listing holds the items seen so far by a paginated list.
+	listLock sync.Mutex
+	listing  []interface{}
+
+	// prevDone is closed once the feed this one replaced has delivered its
+	// deltas, and done once this one has.
+	prevDone <-chan struct{}
+	done     chan struct{}
+
+	// closed is guarded by the informer's deltaLock.
+	closed bool
+	stopCh chan struct{}
+}
+
+// newDeltaFeed returns a new feed for the given namespace and delta handler,
+// taking over the known objects of the given stopped feed, if any.  Its
+// goroutines are tracked by the informer's wait group.
+func newDeltaFeed(namespace string, d *deltaHandlerData, prev *deltaFeed) *deltaFeed {
+	known := cache.NewStore(cache.DeletionHandlingMetaNamespaceKeyFunc)
+	prevDone := make(chan struct{})
+
+	if prev != nil {
+		known = prev.known
+		prevDone = prev.done
+	} else {
+		close(prevDone)
+	}
+
+	f := &deltaFeed{
+		namespace: namespace,
+		handler:   d.handler,
+		known:     known,
+		prevDone:  prevDone,
+		done:      make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		fifo: cache.NewDeltaFIFOWithOptions(cache.DeltaFIFOOptions{
+			KnownObjects:          known,
+			EmitDeltaTypeReplaced: true,
+		}),
+	}
+
+	wg := &d.informer.wg
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(f.done)
+
+		f.run()
+	}()
+
+	if d.resyncPeriod > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			wait.Until(func() {
+				if err := f.fifo.Resync(); err != nil {
+					klog.Errorf("Failed to resync deltas for namespace %q: %v", namespace, err)
+				}
+			}, d.resyncPeriod, f.stopCh)
+		}()
+	}
+
+	return f
+}
+
+// run delivers deltas until the feed is closed and drained.
+func (f *deltaFeed) run() {
+	for {
+		_, err := f.fifo.Pop(func(obj interface{}, _ bool) error {
+			deltas := obj.(cache.Deltas)
+
+			if newest := deltas.Newest(); newest != nil {
+				if newest.Type == cache.Deleted {
+					_ = f.known.Delete(newest.Object)
+				} else {
+					_ = f.known.Update(newest.Object)
+				}
+			}
+
+			f.handler.OnDeltas(f.namespace, deltas)
+
+			return nil
+		})
+		if errors.Is(err, cache.ErrFIFOClosed) {
+			return
+		}
+	}
+}
+
+// list queues a page of a list for the namespace.  Once the last page is in,
+// its items replace the known objects.  Lists and events wait until the feed
+// this one replaced is done, so the known objects are up to date.
+func (f *deltaFeed) list(options metav1.ListOptions, listMeta metav1.ListInterface, items []interface{}) error {
+	<-f.prevDone
+
+	f.listLock.Lock()
+	defer f.listLock.Unlock()
+
+	if options.Continue == "" {
+		f.listing = nil
+	}
+
+	f.listing = append(f.listing, items...)
+
+	if listMeta.GetContinue() != "" {
+		return nil
+	}
+
+	listing := f.listing
+	f.listing = nil
+
+	return f.fifo.Replace(listing, listMeta.GetResourceVersion())
+}
+
+// event queues a watch event for the namespace.
+func (f *deltaFeed) event(eventType watch.EventType, obj interface{}) {
+	<-f.prevDone
+
+	var err error
+
+	switch eventType {
+	case watch.Added:
+		err = f.fifo.Add(obj)
+	case watch.Modified:
+		err = f.fifo.Update(obj)
+	case watch.Deleted:
+		err = f.fifo.Delete(obj)
+	}
+
+	if err != nil {
+		klog.Errorf("Failed to queue deltas for namespace %q: %v", f.namespace, err)
+	}
+}
+
+// close queues deletions for every known object if deleteAll is true, and
+// stops the feed once they are delivered.  Closing a closed feed does nothing.
+// The informer's deltaLock must be held.
+func (f *deltaFeed) close(deleteAll bool) {
+	if f.closed {
+		return
+	}
+
+	f.closed = true
+
+	if deleteAll {
+		for _, obj := range f.known.List() {
+			_ = f.fifo.Delete(obj)
+		}
+	}
+
+	close(f.stopCh)
+	f.fifo.Close()
+}
+
+// AddDeltaHandler adds a handler that is sent the raw deltas for each
+// namespace, which tell apart objects listed by a relist (Replaced), resyncs
+// (Sync), and real changes.  A handler added while the informer is running is
+// first sent Replaced deltas for the contents of each synced namespace, and
+// namespaces that are removed are sent Deleted deltas for their objects.  If
+// resyncPeriod is positive, Sync deltas are sent for every object that often.
+// This is only supported by informers created with a cache.ListerWatcher.
+//
+// Delta handlers are sent copies of the objects, separate from the cache's so
+// they can be transformed on their own.  All delta handlers share one copy of
+// each namespace's objects, which is counted against the budget, and each
+// keeps a map of the objects it has been told about.
+func (i *multiNamespaceInformer) AddDeltaHandler(handler DeltaHandler, resyncPeriod time.Duration,
+) (cache.ResourceEventHandlerRegistration, error) {
+	if i.newListerWatcher == nil {
+		return nil, ErrDeltaHandlerUnsupported
+	}
+
+	i.lock.Lock()
+
+	d := &deltaHandlerData{
+		informer:     i,
+		handler:      handler,
+		resyncPeriod: resyncPeriod,
+		feeds:        make(map[string]*deltaFeed),
+	}
+
+	if err := i.addDeltaHandler(d); err != nil {
+		i.lock.Unlock()
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(d.feeds))
+	for namespace := range d.feeds {
+		namespaces = append(namespaces, namespace)
+	}
+
+	b := i.budget
+	i.lock.Unlock()
+
+	// The budget's callbacks may call back into the informer, so namespaces
+	// are checked against it without the lock held.
+	if b != nil {
+		for _, namespace := range namespaces {
+			b.check(namespace)
+		}
+	}
+
+	return d, nil
+}
+
+// addDeltaHandler adds a feed to the given delta handler for each namespace,
+// sending it the contents of synced namespaces.  The lock must be held.
+func (i *multiNamespaceInformer) addDeltaHandler(d *deltaHandlerData) error {
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for namespace, informer := range i.informers {
+		f := newDeltaFeed(namespace, d, nil)
+		d.feeds[namespace] = f
+
+		if informer.HasSynced() {
+			if err := f.fifo.Replace(informer.GetStore().List(), informer.LastSyncResourceVersion()); err != nil {
+				for _, f := range d.feeds {
+					f.close(false)
+				}
+
+				return err
+			}
+		}
+
+		// Feeds of a stopped informer only deliver what they have, and
+		// are replaced when it is restarted.
+		if i.stopped {
+			f.close(false)
+		}
+	}
+
+	i.deltaHandlers = append(i.deltaHandlers, d)
+
+	for namespace := range i.informers {
+		i.countDeltas(namespace)
+	}
+
+	return nil
+}
+
+// addDeltaFeeds adds a feed for the given namespace to each delta handler
+// that doesn't have a running one.  Feeds are kept when a namespace's informer
+// is replaced, since its list replaces the known objects, and feeds stopped
+// along with the informer are replaced by ones that know the same objects.
+// The lock must be held.
+func (i *multiNamespaceInformer) addDeltaFeeds(namespace string) {
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for _, d := range i.deltaHandlers {
+		if f, ok := d.feeds[namespace]; !ok {
+			d.feeds[namespace] = newDeltaFeed(namespace, d, nil)
+		} else if f.closed {
+			d.feeds[namespace] = newDeltaFeed(namespace, d, f)
+		}
+	}
+
+	i.countDeltas(namespace)
+}
+
+// stopDeltaFeeds stops every delta feed once its deltas are delivered.  The
+// feeds are replaced when the informer is restarted.
+func (i *multiNamespaceInformer) stopDeltaFeeds() {
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for _, d := range i.deltaHandlers {
+		for _, f := range d.feeds {
+			f.close(false)
+		}
+	}
+}
+
+// removeDeltaFeeds sends each delta handler deletions for the objects in the
+// given namespace and stops their feeds.
+func (i *multiNamespaceInformer) removeDeltaFeeds(namespace string) {
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for _, d := range i.deltaHandlers {
+		if f, ok := d.feeds[namespace]; ok {
+			f.close(true)
+			delete(d.feeds, namespace)
+		}
+	}
+}
+
+// removeDeltaHandler removes the given delta handler and stops its feeds.
+// The lock must be held.
+func (i *multiNamespaceInformer) removeDeltaHandler(d *deltaHandlerData) error {
+	if d.informer != i {
+		return fmt.Errorf("registration %v is not from this informer", d)
+	}
+
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	for idx, h := range i.deltaHandlers {
+		if h == d {
+			i.deltaHandlers = append(i.deltaHandlers[:idx], i.deltaHandlers[idx+1:]...)
+			break
+		}
+	}
+
+	for namespace, f := range d.feeds {
+		f.close(false)
+		delete(d.feeds, namespace)
+		i.countDeltas(namespace)
+	}
+
+	return nil
+}
+
+// countDeltas records in the budget whether delta handlers hold a copy of the
+// given namespace's objects.  The lock and deltaLock must be held.
+func (i *multiNamespaceInformer) countDeltas(namespace string) {
+	if i.budget != nil {
+		i.budget.setDeltas(namespace, len(i.deltaHandlers) > 0)
+	}
+}
+
+// deltaFeeds returns the delta feeds for the given namespace, along with the
+// transform for the objects sent to them.
+func (i *multiNamespaceInformer) deltaFeeds(namespace string) ([]*deltaFeed, cache.TransformFunc) {
+	i.deltaLock.Lock()
+	defer i.deltaLock.Unlock()
+
+	var res []*deltaFeed
+	for _, d := range i.deltaHandlers {
+		if f, ok := d.feeds[namespace]; ok {
+			res = append(res, f)
+		}
+	}
+
+	return res, i.deltaTransform
+}
+
+// deltaObject returns a transformed copy of the given object for delta feeds.
+// The informer's cache holds the original, which its own transform may
+// change at any time.
+func deltaObject(obj runtime.Object, transform cache.TransformFunc) (interface{}, error) {
+	obj = obj.DeepCopyObject()
+
+	if transform == nil {
+		return obj, nil
+	}
+
+	return transform(obj)
+}
+
+// listDeltas passes a copy of a list for the given namespace on to its delta
+// feeds.
+func (i *multiNamespaceInformer) listDeltas(namespace string, options metav1.ListOptions, list runtime.Object) {
+	feeds, transform := i.deltaFeeds(namespace)
+	if len(feeds) == 0 {
+		return
+	}
+
+	err := func() error {
+		listMeta, err := meta.ListAccessor(list)
+		if err != nil {
+			return err
+		}
+
+		objs, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+
+		items := make([]interface{}, 0, len(objs))
+		for _, obj := range objs {
+			item, err := deltaObject(obj, transform)
+			if err != nil {
+				return err
+			}
+
+			items = append(items, item)
+		}
+
+		for _, f := range feeds {
+			if err := f.list(options, listMeta, items); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+	if err != nil {
+		klog.Errorf("Failed to queue deltas for namespace %q: %v", namespace, err)
+	}
+}
+
+// watchDeltas returns a watch.Interface passing copies of the events of the
+// given watch on to the namespace's delta feeds.
+func (i *multiNamespaceInformer) watchDeltas(namespace string, w watch.Interface) watch.Interface {
+	return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+		if e.Type != watch.Added && e.Type != watch.Modified && e.Type != watch.Deleted {
+			return e, true
+		}
+
+		feeds, transform := i.deltaFeeds(namespace)
+		if len(feeds) == 0 {
+			return e, true
+		}
+
+		obj, err := deltaObject(e.Object, transform)
+		if err != nil {
+			klog.Errorf("Failed to queue deltas for namespace %q: %v", namespace, err)
+			return e, true
+		}
+
+		for _, f := range feeds {
+			f.event(e.Type, obj)
+		}
+
+		return e, true
+	})
+}
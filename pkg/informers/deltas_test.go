@@ -0,0 +1,267 @@
+package informers_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// deltaRecorder records the type of each delta delivered to it.
+type deltaRecorder struct {
+	lock   sync.Mutex
+	deltas []string
+}
+
+func (r *deltaRecorder) OnDeltas(namespace string, deltas cache.Deltas) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, d := range deltas {
+		key, _ := cache.DeletionHandlingMetaNamespaceKeyFunc(d.Object)
+		r.deltas = append(r.deltas, describeDelta(namespace, d.Type, key))
+	}
+}
+
+func describeDelta(namespace string, deltaType cache.DeltaType, key string) string {
+	return namespace + " " + string(deltaType) + " " + key
+}
+
+func (r *deltaRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.deltas = nil
+}
+
+func (r *deltaRecorder) waitFor(t *testing.T, expected ...string) {
+	t.Helper()
+
+	var got []string
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		got = append([]string(nil), r.deltas...)
+		return reflect.DeepEqual(got, expected), nil
+	})
+	if err != nil {
+		t.Fatalf("\n- got: %v\n- want: %v", got, expected)
+	}
+}
+
+func TestMultiNamespaceInformerDeltaHandler(t *testing.T) {
+	source1 := fcache.NewFakeControllerSource()
+	source1.Add(newPod("ns1", "pod1"))
+
+	source2 := fcache.NewFakeControllerSource()
+	source2.Add(newPod("ns2", "pod1"))
+
+	lws := map[string]cache.ListerWatcher{"ns1": source1}
+	informer := newListerWatcherInformer(&v1.Pod{}, lws)
+
+	lws["ns2"] = source2
+
+	recorder := &deltaRecorder{}
+
+	registration, err := informer.AddDeltaHandler(recorder, 0)
+	if err != nil {
+		t.Fatalf("Failed to add delta handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	source1.Add(newPod("ns1", "pod2"))
+	source1.Modify(newPod("ns1", "pod2"))
+
+	recorder.waitFor(t,
+		"ns1 Replaced ns1/pod1",
+		"ns1 Added ns1/pod2",
+		"ns1 Updated ns1/pod2",
+	)
+
+	recorder.reset()
+
+	// Added namespaces are listed, and removed ones are sent deletions.
+	informer.AddNamespace("ns2")
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	recorder.waitFor(t, "ns2 Replaced ns2/pod1")
+
+	informer.RemoveNamespace("ns2")
+
+	recorder.waitFor(t, "ns2 Replaced ns2/pod1", "ns2 Deleted ns2/pod1")
+
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		t.Fatalf("Failed to remove delta handler: %v", err)
+	}
+}
+
+func TestMultiNamespaceInformerDeltaHandlerAddedLate(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	recorder := &deltaRecorder{}
+
+	registration, err := informer.AddDeltaHandler(recorder, 0)
+	if err != nil {
+		t.Fatalf("Failed to add delta handler: %v", err)
+	}
+
+	cache.WaitForCacheSync(stop, registration.HasSynced)
+
+	source.Delete(newPod("ns1", "pod1"))
+
+	recorder.waitFor(t, "ns1 Replaced ns1/pod1", "ns1 Deleted ns1/pod1")
+}
+
+func TestMultiNamespaceInformerDeltaHandlerUnsupported(t *testing.T) {
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": fcache.NewFakeControllerSource(),
+	})
+
+	_, err := informer.AddDeltaHandler(xnsinformers.DeltaHandlerFunc(func(string, cache.Deltas) {}), 0)
+	if !errors.Is(err, xnsinformers.ErrDeltaHandlerUnsupported) {
+		t.Errorf("Expected unsupported error, got: %v", err)
+	}
+}
+
+func TestMultiNamespaceInformerDeltaHandlerTransform(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	// The transform counts how many times it saw each object.
+	err := informer.SetTransform(func(obj interface{}) (interface{}, error) {
+		pod := obj.(*v1.Pod)
+		pod.Labels = map[string]string{"transforms": pod.Labels["transforms"] + "x"}
+		return pod, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to set transform: %v", err)
+	}
+
+	var (
+		lock sync.Mutex
+		pods []*v1.Pod
+	)
+
+	registration, err := informer.AddDeltaHandler(xnsinformers.DeltaHandlerFunc(func(_ string, deltas cache.Deltas) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		for _, d := range deltas {
+			pods = append(pods, d.Object.(*v1.Pod))
+		}
+	}), 0)
+	if err != nil {
+		t.Fatalf("Failed to add delta handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	source.Modify(newPod("ns1", "pod1"))
+
+	err = wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(pods) == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for deltas")
+	}
+
+	cached, _, _ := informer.GetStore().GetByKey("ns1/pod1")
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// Delta handlers get their own copies, transformed once like the cache's.
+	for _, pod := range append(pods, cached.(*v1.Pod)) {
+		if transforms := pod.Labels["transforms"]; transforms != "x" {
+			t.Errorf("Expected %s to be transformed once, got %q", pod.Name, transforms)
+		}
+	}
+
+	if pods[1] == cached {
+		t.Errorf("Expected delta handlers not to share objects with the cache")
+	}
+}
+
+func TestMultiNamespaceInformerDeltaHandlerStop(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	recorder := &deltaRecorder{}
+
+	registration, err := informer.AddDeltaHandler(recorder, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to add delta handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	// Wait covers the feeds and their resyncs, which stop with the informer.
+	close(stop)
+	informer.Wait()
+	recorder.reset()
+
+	time.Sleep(100 * time.Millisecond)
+
+	recorder.waitFor(t)
+
+	// The feeds of a restarted informer know the objects sent before, so
+	// deletions while it was stopped aren't missed.
+	source.Delete(newPod("ns1", "pod1"))
+
+	stop = make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+
+	err = wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		recorder.lock.Lock()
+		defer recorder.lock.Unlock()
+
+		for _, d := range recorder.deltas {
+			if d == "ns1 Deleted ns1/pod1" {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for pod1 to be deleted")
+	}
+}
@@ -28,6 +28,10 @@ type DynamicSharedInformerFactory interface {
 	SetNamespaces(namespaces []string)
 	ForResource(gvr schema.GroupVersionResource) informers.GenericInformer
 	WaitForCacheSync(stopCh <-chan struct{}) map[schema.GroupVersionResource]bool
+	WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout time.Duration, markDegraded bool) map[schema.GroupVersionResource]SyncReport
+	WaitForCacheSyncWithPriority(stopCh <-chan struct{}, minPriority int) map[schema.GroupVersionResource]bool
+	SaveSnapshots(dir string) error
+	RestoreSnapshots(dir string) error
 	Shutdown()
 }
 
@@ -152,6 +156,50 @@ func (f *dynamicSharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{})
 	return res
 }
 
+// WaitForCacheSyncWithTimeout waits for all started informers' caches to sync
+// until the timeout expires, and returns a report for each of them.  If
+// markDegraded is true, namespaces that haven't synced by then are marked
+// degraded, so the informers report synced for the remaining namespaces.
+func (f *dynamicSharedInformerFactory) WaitForCacheSyncWithTimeout(stopCh <-chan struct{}, timeout time.Duration,
+	markDegraded bool,
+) map[schema.GroupVersionResource]SyncReport {
+	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				informers[informerType] = informer.Informer()
+			}
+		}
+		return informers
+	}()
+
+	return WaitForCacheSyncWithTimeout(stopCh, timeout, markDegraded, informers)
+}
+
+// WaitForCacheSyncWithPriority waits for the namespaces with at least the given
+// priority to sync in all started informers, ignoring lower priority ones.
+func (f *dynamicSharedInformerFactory) WaitForCacheSyncWithPriority(stopCh <-chan struct{},
+	minPriority int,
+) map[schema.GroupVersionResource]bool {
+	informers := func() map[schema.GroupVersionResource]cache.SharedIndexInformer {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		informers := map[schema.GroupVersionResource]cache.SharedIndexInformer{}
+		for informerType, informer := range f.informers {
+			if f.startedInformers[informerType] {
+				informers[informerType] = informer.Informer()
+			}
+		}
+		return informers
+	}()
+
+	return WaitForPrioritySync(stopCh, minPriority, informers)
+}
+
 func (f *dynamicSharedInformerFactory) Shutdown() {
 	f.lock.Lock()
 	f.shuttingDown = true
@@ -161,35 +209,61 @@ func (f *dynamicSharedInformerFactory) Shutdown() {
 	f.wg.Wait()
 }
 
+// SaveSnapshots writes a snapshot of each started informer's caches to a file
+// in the given directory.
+func (f *dynamicSharedInformerFactory) SaveSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	informers := map[string]cache.SharedIndexInformer{}
+	for informerType, informer := range f.informers {
+		if f.startedInformers[informerType] {
+			informers[resourceSnapshotFileName(informerType)] = informer.Informer()
+		}
+	}
+
+	return SaveSnapshots(dir, informers)
+}
+
+// RestoreSnapshots restores informers from the snapshots in the given directory
+// written by SaveSnapshots.  It must be called after the informers have been
+// requested and before the factory is started.
+func (f *dynamicSharedInformerFactory) RestoreSnapshots(dir string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	informers := map[string]cache.SharedIndexInformer{}
+	for informerType, informer := range f.informers {
+		informers[resourceSnapshotFileName(informerType)] = informer.Informer()
+	}
+
+	return RestoreSnapshots(dir, informers)
+}
+
 // NewFilteredDynamicInformer constructs a new informer for a dynamic type.
 func NewFilteredDynamicInformer(client dynamic.Interface, gvr schema.GroupVersionResource, namespaces NamespaceSet, resyncPeriod time.Duration,
 	indexers cache.Indexers, tweakListOptions TweakListOptionsFunc,
 ) informers.GenericInformer {
-	newInformer := func(namespace string) cache.SharedIndexInformer {
-		return cache.NewSharedIndexInformer(
-			&cache.ListWatch{
-				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
-					if tweakListOptions != nil {
-						tweakListOptions(&options)
-					}
-					return client.Resource(gvr).Namespace(namespace).List(context.TODO(), options)
-				},
-				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
-					if tweakListOptions != nil {
-						tweakListOptions(&options)
-					}
-					return client.Resource(gvr).Namespace(namespace).Watch(context.TODO(), options)
-				},
+	newListerWatcher := func(namespace string) cache.ListerWatcher {
+		return &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Resource(gvr).Namespace(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.Resource(gvr).Namespace(namespace).Watch(context.TODO(), options)
 			},
-			&unstructured.Unstructured{},
-			resyncPeriod,
-			indexers,
-		)
+		}
 	}
 
 	return &dynamicInformer{
 		gvr:      gvr,
-		informer: NewMultiNamespaceInformer(namespaces, resyncPeriod, newInformer),
+		informer: NewMultiNamespaceInformerWithListerWatcher(namespaces, &unstructured.Unstructured{}, resyncPeriod, indexers, newListerWatcher),
 	}
 }
 
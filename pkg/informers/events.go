@@ -0,0 +1,161 @@
+package informers
+
+import (
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// eventType identifies the kind of an informer event.
+type eventType int
+
+const (
+	addEvent eventType = iota
+	updateEvent
+	deleteEvent
+)
+
+// event is a single informer event held for later delivery.  For delete
+// events the deleted object is stored in oldObj.
+type event struct {
+	eventType       eventType
+	key             string
+	oldObj          interface{}
+	newObj          interface{}
+	isInInitialList bool
+}
+
+func newAddEvent(obj interface{}, isInInitialList bool) event {
+	return event{eventType: addEvent, key: eventKey(obj), newObj: obj, isInInitialList: isInInitialList}
+}
+
+func newUpdateEvent(oldObj, newObj interface{}) event {
+	return event{eventType: updateEvent, key: eventKey(newObj), oldObj: oldObj, newObj: newObj}
+}
+
+func newDeleteEvent(obj interface{}) event {
+	return event{eventType: deleteEvent, key: eventKey(obj), oldObj: obj}
+}
+
+// eventKey returns the cache key for the given object, which may be a
+// cache.DeletedFinalStateUnknown tombstone.
+func eventKey(obj interface{}) string {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		klog.Errorf("Failed to get key for object: %v", err)
+	}
+
+	return key
+}
+
+// deliver calls the matching method of the given handler.
+func (e event) deliver(handler cache.ResourceEventHandler) {
+	switch e.eventType {
+	case addEvent:
+		handler.OnAdd(e.newObj, e.isInInitialList)
+	case updateEvent:
+		handler.OnUpdate(e.oldObj, e.newObj)
+	case deleteEvent:
+		handler.OnDelete(e.oldObj)
+	}
+}
+
+// merge combines an event with a later event for the same key.  The returned
+// boolean is false if the two events cancel each other out.
+func (e event) merge(next event) (event, bool) {
+	switch e.eventType {
+	case addEvent:
+		switch next.eventType {
+		case deleteEvent:
+			return event{}, false // Never seen, so nothing to deliver.
+		default:
+			e.newObj = next.newObj
+			return e, true
+		}
+	case updateEvent:
+		switch next.eventType {
+		case deleteEvent:
+			return next, true
+		default:
+			e.newObj = next.newObj
+			return e, true
+		}
+	default: // deleteEvent
+		switch next.eventType {
+		case deleteEvent:
+			return next, true
+		default:
+			// The object was deleted and created again, which looks like an
+			// update to anyone who never saw the deletion.
+			oldObj := e.oldObj
+			if tombstone, ok := oldObj.(cache.DeletedFinalStateUnknown); ok {
+				oldObj = tombstone.Obj
+			}
+			return newUpdateEvent(oldObj, next.newObj), true
+		}
+	}
+}
+
+// eventQueue holds events coalesced by key.  Events are drained in the order
+// their keys were first queued.
+type eventQueue struct {
+	events map[string]event
+	keys   []string
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{events: make(map[string]event)}
+}
+
+// push adds an event to the queue, merging it with any queued event for the
+// same key.
+func (q *eventQueue) push(e event) {
+	prev, ok := q.events[e.key]
+	if !ok {
+		q.events[e.key] = e
+		q.keys = append(q.keys, e.key)
+		return
+	}
+
+	if merged, ok := prev.merge(e); ok {
+		q.events[e.key] = merged
+	} else {
+		delete(q.events, e.key)
+	}
+}
+
+// len returns the number of queued events.
+func (q *eventQueue) len() int {
+	return len(q.events)
+}
+
+// drain empties the queue and returns its events.
+func (q *eventQueue) drain() []event {
+	res := make([]event, 0, len(q.events))
+	for _, key := range q.keys {
+		// A key may appear more than once if its events were canceled out
+		// and it was queued again, so only take each event once.
+		if e, ok := q.events[key]; ok {
+			res = append(res, e)
+			delete(q.events, key)
+		}
+	}
+
+	q.keys = nil
+
+	return res
+}
+
+// pop removes and returns the oldest queued event.
+func (q *eventQueue) pop() (event, bool) {
+	for len(q.keys) > 0 {
+		key := q.keys[0]
+		q.keys = q.keys[1:]
+
+		if e, ok := q.events[key]; ok {
+			delete(q.events, key)
+			return e, true
+		}
+	}
+
+	return event{}, false
+}
@@ -0,0 +1,125 @@
+package informers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+// watchExclusions makes the informer drop the objects of namespaces excluded
+// by the given set, and relist when the exclusions change.  Informers created
+// without a cache.ListerWatcher can't filter what their informers cache, so
+// they watch every namespace.
+func (i *multiNamespaceInformer) watchExclusions(exclusions ExclusionNamespaceSet) {
+	if i.newListerWatcher == nil {
+		klog.Warningf("Informer was created without a cache.ListerWatcher, so namespaces can't be excluded")
+		return
+	}
+
+	i.exclusions = exclusions
+	i.exclusionWatches = make(map[*expiringWatch]struct{})
+
+	exclusions.AddExclusionHandler(i.exclusionsChanged)
+}
+
+// exclusionsChanged expires the current watches, so objects are listed again
+// with the new exclusions.  Lists in progress see the changed generation when
+// their watches start.
+func (i *multiNamespaceInformer) exclusionsChanged() {
+	i.exclusionLock.Lock()
+	defer i.exclusionLock.Unlock()
+
+	i.exclusionGeneration++
+
+	for w := range i.exclusionWatches {
+		w.expire()
+	}
+
+	klog.V(4).Infof("Excluded namespaces changed to %q, relisting", i.exclusions.Excluded())
+}
+
+// getExclusionGeneration returns the number of changes to the exclusions.
+func (i *multiNamespaceInformer) getExclusionGeneration() int64 {
+	i.exclusionLock.Lock()
+	defer i.exclusionLock.Unlock()
+
+	return i.exclusionGeneration
+}
+
+// isExcluded reports whether the given object is in an excluded namespace.
+// Objects without metadata, such as the status of watch errors, never are.
+func (i *multiNamespaceInformer) isExcluded(obj runtime.Object) bool {
+	if i.exclusions == nil {
+		return false
+	}
+
+	o, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+
+	return i.exclusions.IsExcluded(o.GetNamespace())
+}
+
+// filterExcluded returns the given list without the objects in excluded
+// namespaces.
+func (i *multiNamespaceInformer) filterExcluded(list runtime.Object) (runtime.Object, error) {
+	if i.exclusions == nil {
+		return list, nil
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		if !i.isExcluded(item) {
+			kept = append(kept, item)
+		}
+	}
+
+	if len(kept) == len(items) {
+		return list, nil
+	}
+
+	if err := meta.SetList(list, kept); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// excludeWatch returns a watch.Interface dropping the events of the given
+// watch for objects in excluded namespaces.  The watch is expired right away if
+// the exclusions changed since the given generation, which the list it
+// follows was filtered with.
+func (i *multiNamespaceInformer) excludeWatch(w watch.Interface, generation int64) watch.Interface {
+	if i.exclusions == nil {
+		return w
+	}
+
+	ew := newExpiringWatch(w, "excluded namespaces changed", func(e watch.Event) bool {
+		return !i.isExcluded(e.Object)
+	}, i.untrackExclusionWatch)
+
+	i.exclusionLock.Lock()
+	i.exclusionWatches[ew] = struct{}{}
+	if i.exclusionGeneration != generation {
+		ew.expire()
+	}
+	i.exclusionLock.Unlock()
+
+	ew.start()
+
+	return ew
+}
+
+func (i *multiNamespaceInformer) untrackExclusionWatch(w *expiringWatch) {
+	i.exclusionLock.Lock()
+	defer i.exclusionLock.Unlock()
+
+	delete(i.exclusionWatches, w)
+}
@@ -0,0 +1,145 @@
+package informers
+
+import (
+	"sync"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceHandler wraps an event handler added to the informer for a single
+// namespace.  Events are passed through to the wrapped handler unless delivery
+// for the namespace is paused, in which case they are queued.  Only queued
+// events are keyed, so handlers that are never paused pay nothing extra.
+type namespaceHandler struct {
+	namespace string
+	handler   cache.ResourceEventHandler
+
+	lock    sync.Mutex
+	paused  bool
+	pending *eventQueue
+}
+
+var _ cache.ResourceEventHandler = &namespaceHandler{}
+
+func newNamespaceHandler(namespace string, handler cache.ResourceEventHandler, paused bool) *namespaceHandler {
+	return &namespaceHandler{
+		namespace: namespace,
+		handler:   handler,
+		paused:    paused,
+		pending:   newEventQueue(),
+	}
+}
+
+func (h *namespaceHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.paused {
+		h.pending.push(newAddEvent(obj, isInInitialList))
+		return
+	}
+
+	h.handler.OnAdd(obj, isInInitialList)
+}
+
+func (h *namespaceHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.paused {
+		h.pending.push(newUpdateEvent(oldObj, newObj))
+		return
+	}
+
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h *namespaceHandler) OnDelete(obj interface{}) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.paused {
+		h.pending.push(newDeleteEvent(obj))
+		return
+	}
+
+	h.handler.OnDelete(obj)
+}
+
+// pause stops delivery, queueing events until resume is called.
+func (h *namespaceHandler) pause() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.paused = true
+}
+
+// resume delivers any queued events and restarts normal delivery.  The lock is
+// held while delivering so that newer events can't overtake queued ones.
+func (h *namespaceHandler) resume() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.paused = false
+
+	for _, e := range h.pending.drain() {
+		e.deliver(h.handler)
+	}
+}
+
+// namespaceFeed feeds the events of one namespace's informer into a structure
+// maintained by the cross-namespace informer, such as a global index.  Feeds
+// bypass pausing, and are closed when their informer is removed or replaced so
+// late events from a stopped informer can't leave stale objects behind.
+type namespaceFeed struct {
+	handler      cache.ResourceEventHandler
+	registration cache.ResourceEventHandlerRegistration
+
+	lock   sync.Mutex
+	closed bool
+}
+
+var _ cache.ResourceEventHandler = &namespaceFeed{}
+
+func (f *namespaceFeed) OnAdd(obj interface{}, isInInitialList bool) {
+	f.handle(func() { f.handler.OnAdd(obj, isInInitialList) })
+}
+
+func (f *namespaceFeed) OnUpdate(oldObj, newObj interface{}) {
+	f.handle(func() { f.handler.OnUpdate(oldObj, newObj) })
+}
+
+func (f *namespaceFeed) OnDelete(obj interface{}) {
+	f.handle(func() { f.handler.OnDelete(obj) })
+}
+
+func (f *namespaceFeed) handle(update func()) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.closed {
+		update()
+	}
+}
+
+// close stops the feed.  Once it returns, the feed makes no more updates.
+func (f *namespaceFeed) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.closed = true
+}
+
+// isClosed returns true if the feed has been closed.
+func (f *namespaceFeed) isClosed() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.closed
+}
+
+// hasSynced returns true once the feed has been sent the initial list of its
+// informer, or if it has been closed.
+func (f *namespaceFeed) hasSynced() bool {
+	return f.isClosed() || f.registration.HasSynced()
+}
@@ -0,0 +1,318 @@
+package informers
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// indexMirror maintains indexers added after a namespace's informer started.
+// Upstream informers refuse new indexers once they are running, so the
+// informer's objects are mirrored into a separate indexer by an event handler.
+// The mirror shares the informer's objects but keeps its own map of keys and
+// indexes, and it lags behind the informer's store by however long the event
+// handler takes to catch up.
+type indexMirror struct {
+	indexer      cache.Indexer
+	registration cache.ResourceEventHandlerRegistration
+
+	// lock orders the mirror's events with its initial fill, and seen holds
+	// the keys of the objects its events have touched since the fill began.
+	// seen is nil once the mirror has been filled.
+	lock sync.Mutex
+	seen sets.Set[string]
+}
+
+var _ cache.ResourceEventHandler = &indexMirror{}
+
+// newIndexMirror returns a new mirror with the given indexers for the given
+// running informer.  The mirror is filled from the informer's store before it
+// is returned, so its indexes are complete right away.
+func newIndexMirror(informer cache.SharedIndexInformer, indexers cache.Indexers) (*indexMirror, error) {
+	m := &indexMirror{
+		indexer: cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, indexers),
+		seen:    sets.New[string](),
+	}
+
+	registration, err := informer.AddEventHandler(m)
+	if err != nil {
+		return nil, err
+	}
+
+	m.registration = registration
+	m.fill(informer.GetStore().List())
+
+	return m, nil
+}
+
+// fill adds the given objects to the mirror.  Objects its events have already
+// touched are skipped, since the events are more recent than the objects.
+func (m *indexMirror) fill(objs []interface{}) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, obj := range objs {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil && !m.seen.Has(key) {
+			_ = m.indexer.Add(obj)
+		}
+	}
+
+	m.seen = nil
+}
+
+// close stops mirroring the given informer's events.
+func (m *indexMirror) close(informer cache.SharedIndexInformer) {
+	if m.registration != nil {
+		_ = informer.RemoveEventHandler(m.registration)
+	}
+}
+
+// apply applies an event for the given object to the mirror.
+func (m *indexMirror) apply(obj interface{}, update func(obj interface{}) error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.seen != nil {
+		if key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj); err == nil {
+			m.seen.Insert(key)
+		}
+	}
+
+	_ = update(obj)
+}
+
+func (m *indexMirror) OnAdd(obj interface{}, _ bool) {
+	m.apply(obj, m.indexer.Add)
+}
+
+func (m *indexMirror) OnUpdate(_, newObj interface{}) {
+	m.apply(newObj, m.indexer.Update)
+}
+
+func (m *indexMirror) OnDelete(obj interface{}) {
+	m.apply(obj, m.indexer.Delete)
+}
+
+// namespaceIndexer is the cache.Indexer for a namespace with mirrored indexes.
+// Index queries for mirrored indexes are answered by the mirror holding them,
+// and everything else goes to the informer's own indexer.
+type namespaceIndexer struct {
+	cache.Indexer
+	mirrors []*indexMirror
+}
+
+var _ cache.Indexer = &namespaceIndexer{}
+
+// indexerFor returns the indexer holding the given index.
+func (n *namespaceIndexer) indexerFor(indexName string) cache.Indexer {
+	for _, m := range n.mirrors {
+		if _, ok := m.indexer.GetIndexers()[indexName]; ok {
+			return m.indexer
+		}
+	}
+
+	return n.Indexer
+}
+
+func (n *namespaceIndexer) GetIndexers() cache.Indexers {
+	res := cache.Indexers{}
+	for k, v := range n.Indexer.GetIndexers() {
+		res[k] = v
+	}
+
+	for _, m := range n.mirrors {
+		for k, v := range m.indexer.GetIndexers() {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+func (n *namespaceIndexer) Index(indexName string, obj interface{}) ([]interface{}, error) {
+	return n.indexerFor(indexName).Index(indexName, obj)
+}
+
+func (n *namespaceIndexer) IndexKeys(indexName, indexedValue string) ([]string, error) {
+	return n.indexerFor(indexName).IndexKeys(indexName, indexedValue)
+}
+
+func (n *namespaceIndexer) ListIndexFuncValues(indexName string) []string {
+	return n.indexerFor(indexName).ListIndexFuncValues(indexName)
+}
+
+func (n *namespaceIndexer) ByIndex(indexName, indexedValue string) ([]interface{}, error) {
+	return n.indexerFor(indexName).ByIndex(indexName, indexedValue)
+}
+
+func (n *namespaceIndexer) AddIndexers(_ cache.Indexers) error {
+	return fmt.Errorf("indexers must be added with the informer's AddIndexers")
+}
+
+// indexerConflicts returns the sorted names of the given indexers that the
+// informer already has.  The lock must be held.
+func (i *multiNamespaceInformer) indexerConflicts(indexers cache.Indexers) []string {
+	existing := sets.KeySet(i.allIndexers())
+	return sets.List(existing.Intersection(sets.KeySet(indexers)))
+}
+
+// allIndexers returns every indexer the informer has, whether per namespace or
+// global.  The lock must be held.
+func (i *multiNamespaceInformer) allIndexers() cache.Indexers {
+	res := cache.Indexers{}
+
+	add := func(indexers cache.Indexers) {
+		for k, v := range indexers {
+			res[k] = v
+		}
+	}
+
+	for _, idx := range i.indexers {
+		add(idx)
+	}
+
+	for namespace := range i.informers {
+		add(i.namespaceIndexer(namespace).GetIndexers())
+	}
+
+	for _, m := range i.globalMirrors {
+		add(m.indexer.GetIndexers())
+	}
+
+	return res
+}
+
+// namespaceIndexer returns the cache.Indexer for the given namespace, which
+// includes any mirrored indexes.  The lock must be held.
+func (i *multiNamespaceInformer) namespaceIndexer(namespace string) cache.Indexer {
+	indexer := i.informers[namespace].GetIndexer()
+
+	if mirrors := i.indexMirrors[namespace]; len(mirrors) > 0 {
+		return &namespaceIndexer{Indexer: indexer, mirrors: mirrors}
+	}
+
+	return indexer
+}
+
+// globalIndexer is implemented by cross-namespace indexers with global indexes,
+// which cover every namespace in a single index.
+type globalIndexer interface {
+	globalIndex(indexName string) cache.Indexer
+	globalIndexers() cache.Indexers
+}
+
+// AddGlobalIndexers adds indexers maintained across all namespaces.  Unlike
+// indexers added with AddIndexers, which are queried per namespace, each global
+// index is kept in a single cache.Indexer fed by the events of every namespace.
+// Lookups in the indexer returned by GetIndexer therefore cost the same no
+// matter how many namespaces are tracked.  Global indexers may be added at any
+// time, and their indexes are built from the existing caches.
+func (i *multiNamespaceInformer) AddGlobalIndexers(indexers cache.Indexers) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if conflicts := i.indexerConflicts(indexers); len(conflicts) > 0 {
+		return fmt.Errorf("indexer conflict: %v", conflicts)
+	}
+
+	m := &indexMirror{
+		indexer: cache.NewIndexer(cache.DeletionHandlingMetaNamespaceKeyFunc, indexers),
+	}
+
+	i.globalMirrors = append(i.globalMirrors, m)
+
+	for namespace, informer := range i.informers {
+		if _, err := i.addFeed(namespace, informer, newGlobalIndexFeed(m)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// globalIndex returns the global indexer holding the given index, or nil if
+// there is no such global index.
+func (i *multiNamespaceInformer) globalIndex(indexName string) cache.Indexer {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for _, m := range i.globalMirrors {
+		if _, ok := m.indexer.GetIndexers()[indexName]; ok {
+			return m.indexer
+		}
+	}
+
+	return nil
+}
+
+// globalIndexers returns the informer's global indexers.
+func (i *multiNamespaceInformer) globalIndexers() cache.Indexers {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	res := cache.Indexers{}
+	for _, m := range i.globalMirrors {
+		for k, v := range m.indexer.GetIndexers() {
+			res[k] = v
+		}
+	}
+
+	return res
+}
+
+// globalIndexFeed feeds one namespace's objects into a global index, keeping
+// track of the keys it added so they can be removed with the namespace.  Its
+// events are serialized by the namespaceFeed it is wrapped in.
+type globalIndexFeed struct {
+	mirror *indexMirror
+	keys   sets.Set[string]
+}
+
+var _ cache.ResourceEventHandler = &globalIndexFeed{}
+
+func newGlobalIndexFeed(mirror *indexMirror) *globalIndexFeed {
+	return &globalIndexFeed{mirror: mirror, keys: sets.New[string]()}
+}
+
+func (g *globalIndexFeed) OnAdd(obj interface{}, isInInitialList bool) {
+	g.track(obj, true)
+	g.mirror.OnAdd(obj, isInInitialList)
+}
+
+func (g *globalIndexFeed) OnUpdate(oldObj, newObj interface{}) {
+	g.track(newObj, true)
+	g.mirror.OnUpdate(oldObj, newObj)
+}
+
+func (g *globalIndexFeed) OnDelete(obj interface{}) {
+	g.track(obj, false)
+	g.mirror.OnDelete(obj)
+}
+
+func (g *globalIndexFeed) track(obj interface{}, present bool) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+
+	if present {
+		g.keys.Insert(key)
+	} else {
+		g.keys.Delete(key)
+	}
+}
+
+// remove removes the objects added by the feed from the global index.  The
+// feed must be closed.
+func (g *globalIndexFeed) remove() {
+	for key := range g.keys {
+		obj, exists, err := g.mirror.indexer.GetByKey(key)
+		if err == nil && exists {
+			_ = g.mirror.indexer.Delete(obj)
+		}
+	}
+
+	g.keys = sets.New[string]()
+}
@@ -0,0 +1,199 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+const byNodeIndex = "byNode"
+
+func indexByNode(obj interface{}) ([]string, error) {
+	return []string{obj.(*v1.Pod).Spec.NodeName}, nil
+}
+
+func newPodOnNode(namespace, name, node string) *v1.Pod {
+	pod := newPod(namespace, name)
+	pod.Spec.NodeName = node
+
+	return pod
+}
+
+// indexKeys returns the sorted keys of the objects with the given index value.
+func indexKeys(indexer cache.Indexer, indexName, value string) ([]string, error) {
+	objs, err := indexer.ByIndex(indexName, value)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, obj := range objs {
+		key, _ := cache.MetaNamespaceKeyFunc(obj)
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// waitForIndex waits until the given index value holds exactly the given keys.
+func waitForIndex(t *testing.T, indexer cache.Indexer, indexName, value string, keys ...string) {
+	t.Helper()
+
+	sort.Strings(keys)
+
+	var got []string
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		var err error
+		if got, err = indexKeys(indexer, indexName, value); err != nil {
+			return false, err
+		}
+
+		return reflect.DeepEqual(got, keys), nil
+	})
+	if err != nil {
+		t.Fatalf("Index %s=%s: expected %v, got %v: %v", indexName, value, keys, got, err)
+	}
+}
+
+func TestMultiNamespaceInformerAddIndexersWhileRunning(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+	}
+
+	sources["ns1"].Add(newPodOnNode("ns1", "pod1", "node1"))
+	sources["ns1"].Add(newPodOnNode("ns1", "pod2", "node2"))
+	sources["ns2"].Add(newPodOnNode("ns2", "pod3", "node1"))
+
+	namespaceSet := xnsinformers.NewNamespaceSet("ns1")
+	informer := xnsinformers.NewMultiNamespaceInformer(namespaceSet, 0, func(namespace string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(sources[namespace], &v1.Pod{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	if err := informer.AddIndexers(cache.Indexers{byNodeIndex: indexByNode}); err != nil {
+		t.Fatalf("Failed to add indexers: %v", err)
+	}
+
+	indexer := informer.GetIndexer()
+
+	// The index is built from the existing cache before AddIndexers returns.
+	if got, err := indexKeys(indexer, byNodeIndex, "node1"); err != nil || !reflect.DeepEqual(got, []string{"ns1/pod1"}) {
+		t.Errorf("Expected [ns1/pod1] right after adding indexers, got %v: %v", got, err)
+	}
+
+	// The index is kept up to date.
+	sources["ns1"].Add(newPodOnNode("ns1", "pod4", "node1"))
+	sources["ns1"].Delete(newPodOnNode("ns1", "pod1", "node1"))
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns1/pod4")
+
+	// Existing indexes are unaffected.
+	waitForIndex(t, indexer, cache.NamespaceIndex, "ns1", "ns1/pod2", "ns1/pod4")
+
+	// Namespaces added later get the indexers too.
+	namespaceSet.SetNamespaces([]string{"ns1", "ns2"})
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns1/pod4", "ns2/pod3")
+
+	if err := informer.AddIndexers(cache.Indexers{byNodeIndex: indexByNode}); err == nil {
+		t.Errorf("Expected an error adding a conflicting indexer")
+	}
+}
+
+func TestMultiNamespaceInformerGlobalIndexers(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+		"ns3": fcache.NewFakeControllerSource(),
+	}
+
+	sources["ns1"].Add(newPodOnNode("ns1", "pod1", "node1"))
+	sources["ns2"].Add(newPodOnNode("ns2", "pod2", "node1"))
+	sources["ns3"].Add(newPodOnNode("ns3", "pod3", "node1"))
+
+	namespaceSet := xnsinformers.NewNamespaceSet("ns1", "ns2")
+	informer := xnsinformers.NewMultiNamespaceInformer(namespaceSet, 0, func(namespace string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(sources[namespace], &v1.Pod{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+
+	if err := informer.AddGlobalIndexers(cache.Indexers{byNodeIndex: indexByNode}); err != nil {
+		t.Fatalf("Failed to add global indexers: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	indexer := informer.GetIndexer()
+
+	if _, ok := indexer.GetIndexers()[byNodeIndex]; !ok {
+		t.Errorf("Expected %q in indexers: %v", byNodeIndex, indexer.GetIndexers())
+	}
+
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns1/pod1", "ns2/pod2")
+
+	sources["ns1"].Modify(newPodOnNode("ns1", "pod1", "node2"))
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns2/pod2")
+	waitForIndex(t, indexer, byNodeIndex, "node2", "ns1/pod1")
+
+	// Namespaces are added to and removed from the global index.
+	namespaceSet.SetNamespaces([]string{"ns1", "ns3"})
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns3/pod3")
+
+	if err := informer.AddGlobalIndexers(cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}); err == nil {
+		t.Errorf("Expected an error adding a conflicting global indexer")
+	}
+}
+
+func TestMultiNamespaceInformerGlobalIndexersRemoveNamespaceAll(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		metav1.NamespaceAll: fcache.NewFakeControllerSource(),
+		"ns1":               fcache.NewFakeControllerSource(),
+	}
+
+	sources[metav1.NamespaceAll].Add(newPodOnNode("ns1", "pod1", "node1"))
+	sources[metav1.NamespaceAll].Add(newPodOnNode("ns2", "pod2", "node1"))
+	sources["ns1"].Add(newPodOnNode("ns1", "pod1", "node1"))
+
+	namespaceSet := xnsinformers.NewNamespaceSet(metav1.NamespaceAll)
+	informer := xnsinformers.NewMultiNamespaceInformer(namespaceSet, 0, func(namespace string) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(sources[namespace], &v1.Pod{}, 0,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+
+	if err := informer.AddGlobalIndexers(cache.Indexers{byNodeIndex: indexByNode}); err != nil {
+		t.Fatalf("Failed to add global indexers: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	indexer := informer.GetIndexer()
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns1/pod1", "ns2/pod2")
+
+	// Objects of every namespace are removed along with metav1.NamespaceAll.
+	namespaceSet.SetNamespaces([]string{"ns1"})
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+	waitForIndex(t, indexer, byNodeIndex, "node1", "ns1/pod1")
+}
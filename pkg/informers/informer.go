@@ -1,12 +1,18 @@
 package informers
 
 import (
+	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/maistra/xns-informer/pkg/internal/sets"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/klog/v2"
 )
 
@@ -22,15 +28,180 @@ type MultiNamespaceInformer interface {
 	AddNamespace(namespace string)
 	RemoveNamespace(namespace string)
 	GetIndexers() map[string]cache.Indexer
+
+	// PauseNamespace stops delivering events for the given namespace to the
+	// informer's event handlers.  The namespace's cache is still kept up to
+	// date, and events are queued and coalesced by key until it is resumed.
+	PauseNamespace(namespace string)
+
+	// ResumeNamespace delivers the events queued while the given namespace
+	// was paused as one compacted batch and restarts normal delivery.
+	ResumeNamespace(namespace string)
+
+	// SaveSnapshot writes the contents and resource version of each synced
+	// namespace's cache to a file at the given path.
+	SaveSnapshot(path string) error
+
+	// RestoreSnapshot reads a snapshot written by SaveSnapshot.  Namespaces
+	// found in the snapshot start out with its contents and watch from its
+	// resource versions instead of listing everything again.  This must be
+	// called before the informer is started.
+	RestoreSnapshot(path string) error
+
+	// AddEventHandlerWithOptions adds an event handler like
+	// AddEventHandlerWithResyncPeriod, with options to coalesce and rate
+	// limit the events delivered to it.
+	AddEventHandlerWithOptions(handler cache.ResourceEventHandler, resyncPeriod time.Duration,
+		opts ...HandlerOption) (cache.ResourceEventHandlerRegistration, error)
+
+	// UnsyncedNamespaces returns the namespaces that haven't synced yet.
+	UnsyncedNamespaces() []string
+
+	// MarkNamespacesDegraded makes HasSynced ignore the given namespaces
+	// until they have synced.
+	MarkNamespacesDegraded(namespaces ...string)
+
+	// DegradedNamespaces returns the degraded namespaces that still haven't
+	// synced.
+	DegradedNamespaces() []string
+
+	// SetWatchdog enables relisting namespaces whose watches go without any
+	// events for longer than the configured threshold.
+	SetWatchdog(config WatchdogConfig) error
+
+	// WatchStats returns the watch activity of each namespace tracked by the
+	// watchdog.
+	WatchStats() map[string]NamespaceWatchStats
+
+	// SetBudget limits the number and size of the objects cached by the
+	// informer.  Namespaces over budget are dropped from the cache until
+	// they fit again.
+	SetBudget(config BudgetConfig) error
+
+	// BudgetUsage returns the usage of each namespace counted against the
+	// informer's budget.
+	BudgetUsage() map[string]NamespaceUsage
+
+	// OverBudgetNamespaces returns the namespaces that are over budget.
+	OverBudgetNamespaces() []string
+
+	// EnableJournal keeps a journal of the given number of most recent event
+	// deliveries to the informer's event handlers.
+	EnableJournal(size int)
+
+	// Journal returns the entries in the informer's journal, oldest first.
+	Journal() []JournalEntry
+
+	// DumpJournal writes the entries in the informer's journal to the given
+	// writer as JSON lines.
+	DumpJournal(w io.Writer) error
+
+	// AddDeltaHandler adds a handler that is sent the raw cache.Deltas of
+	// each namespace.
+	AddDeltaHandler(handler DeltaHandler, resyncPeriod time.Duration) (cache.ResourceEventHandlerRegistration, error)
+
+	// HandlerStats returns the worker pool stats of a handler added with the
+	// WithWorkerPool option.
+	HandlerStats(registration cache.ResourceEventHandlerRegistration) (HandlerStats, bool)
+
+	// AddGlobalIndexers adds indexers maintained in a single index across all
+	// namespaces, instead of one index per namespace.  Lookups for these
+	// indexes through GetIndexer don't depend on the number of namespaces.
+	AddGlobalIndexers(indexers cache.Indexers) error
+
+	// HasSyncedPriority returns true once every namespace with at least the
+	// given priority has synced.
+	HasSyncedPriority(minPriority int) bool
+
+	// Snapshot returns a read-only cache.Indexer holding the contents of
+	// every namespace at a single point in time.
+	Snapshot() cache.Indexer
+
+	// Stop stops all per-namespace informers, as if the stop channel passed
+	// to Run had been closed.  A stopped informer may be run again.
+	Stop()
+
+	// Wait blocks until the goroutines of all per-namespace informers started
+	// by the informer have exited.
+	Wait()
 }
 
 // NewInformerFunc returns a new informer for a given namespace.
 type NewInformerFunc func(namespace string) cache.SharedIndexInformer
 
-// eventHandlerData holds an event handler and its resync period.
+// NewListerWatcherFunc returns a new cache.ListerWatcher for a given namespace.
+type NewListerWatcherFunc func(namespace string) cache.ListerWatcher
+
+// eventHandlerData holds an event handler, its resync period, and its
+// registrations with each namespace's informer.  It is handed out as the
+// cache.ResourceEventHandlerRegistration for the handler.
 type eventHandlerData struct {
+	informer     *multiNamespaceInformer
 	handler      cache.ResourceEventHandler
 	resyncPeriod time.Duration
+	namespaces   map[string]*namespaceRegistration
+
+	// options controls event delivery, and limiter is the rate limiter shared
+	// by all namespaces, if any.
+	options handlerOptions
+	limiter flowcontrol.RateLimiter
+
+	// pool is the handler's worker pool, if any.
+	pool *workerPool
+}
+
+// namespaceRegistration is an event handler's registration with the informer
+// for a single namespace.
+type namespaceRegistration struct {
+	handler      *namespaceHandler
+	throttled    *throttledHandler
+	registration cache.ResourceEventHandlerRegistration
+}
+
+var _ cache.ResourceEventHandlerRegistration = &eventHandlerData{}
+
+// target returns the handler that events for the handler are delivered to,
+// which is its worker pool if it has one.
+func (d *eventHandlerData) target() cache.ResourceEventHandler {
+	if d.pool != nil {
+		return d.pool
+	}
+
+	return d.handler
+}
+
+// HasSynced reports whether the handler has been sent the initial list of
+// every namespace that isn't degraded.  Handlers are sent the initial lists
+// again when a stopped informer is restarted, so it is false while stopped.
+func (d *eventHandlerData) HasSynced() bool {
+	if !d.informer.namespaces.Initialized() {
+		return false
+	}
+
+	d.informer.lock.Lock()
+	defer d.informer.lock.Unlock()
+
+	if d.informer.stopped {
+		return false
+	}
+
+	for ns, r := range d.namespaces {
+		if d.informer.degradedNamespaces.Contains(ns) {
+			continue
+		}
+
+		if r.registration == nil || !r.registration.HasSynced() {
+			return false
+		}
+	}
+
+	// Initial events are queued for the worker pool before the namespaces'
+	// registrations report synced, so check the pool last.
+	if d.pool != nil && !d.pool.hasSynced() {
+		return false
+	}
+
+	return true
 }
 
 // multiNamespaceInformer satisfies the SharedIndexInformer interface and
@@ -40,7 +211,8 @@ type multiNamespaceInformer struct {
 	informers     map[string]cache.SharedIndexInformer
 	stopChans     map[string]chan struct{}
 	errorHandler  cache.WatchErrorHandler
-	eventHandlers []eventHandlerData
+	transform     cache.TransformFunc
+	eventHandlers []*eventHandlerData
 	indexers      []cache.Indexers
 	resyncPeriod  time.Duration
 	lock          sync.Mutex
@@ -49,7 +221,68 @@ type multiNamespaceInformer struct {
 	namespaces    NamespaceSet
 	newInformer   NewInformerFunc
 
-	registrationHandlers map[string]cache.ResourceEventHandlerRegistration
+	// runStopCh is closed by Stop to end the current run, and wg tracks the
+	// goroutines of the per-namespace informers.
+	runStopCh chan struct{}
+	wg        sync.WaitGroup
+
+	// exampleObject and newListerWatcher are only set for informers created
+	// with NewMultiNamespaceInformerWithListerWatcher.  restored holds the
+	// namespace lists read from a snapshot that haven't been used yet.
+	exampleObject    runtime.Object
+	newListerWatcher NewListerWatcherFunc
+	restored         map[string]runtime.Object
+
+	// pausedNamespaces holds the namespaces whose event delivery is paused,
+	// and degradedNamespaces holds those HasSynced ignores until they sync.
+	pausedNamespaces   sets.Set
+	degradedNamespaces sets.Set
+
+	// pendingNamespaces holds the namespaces whose informers are waiting for
+	// higher priority namespaces to sync before they are started.
+	pendingNamespaces sets.Set
+
+	// indexMirrors holds the indexers added after each namespace's informer
+	// was started, and globalMirrors holds the global indexers.
+	indexMirrors  map[string][]*indexMirror
+	globalMirrors []*indexMirror
+
+	// snapshots holds the copy of every namespace's objects used for Snapshot,
+	// or nil until Snapshot is first called.
+	snapshots *snapshotStore
+
+	// watchdog detects namespaces whose watches stopped delivering events,
+	// or is nil if it hasn't been set.
+	watchdog *watchdog
+
+	// budget limits the objects cached by the informer, or is nil if it
+	// hasn't been set.
+	budget *budget
+
+	// journal records the events delivered to event handlers, or is nil if
+	// journaling isn't enabled.  Handlers may be called with the lock held,
+	// so it is kept outside of it.
+	journal atomic.Pointer[eventJournal]
+
+	// deltaHandlers holds the handlers added with AddDeltaHandler.  They are
+	// fed from list and watch calls, which don't hold the main lock, so they
+	// have their own, which also guards the copy of the transform they use.
+	deltaLock      sync.Mutex
+	deltaHandlers  []*deltaHandlerData
+	deltaTransform cache.TransformFunc
+
+	// feeds holds each namespace's feeds into the global indexes and snapshot
+	// store.
+	feeds map[string][]*namespaceFeed
+
+	// exclusions is set when the namespace set is an ExclusionNamespaceSet.
+	// Its changes expire the watches in exclusionWatches, and are counted by
+	// exclusionGeneration.  These are used from list and watch calls and the
+	// set's handlers, so they have their own lock.
+	exclusions          ExclusionNamespaceSet
+	exclusionLock       sync.Mutex
+	exclusionWatches    map[*expiringWatch]struct{}
+	exclusionGeneration int64
 }
 
 var _ cache.SharedIndexInformer = &multiNamespaceInformer{}
@@ -58,24 +291,66 @@ var _ cache.SharedIndexInformer = &multiNamespaceInformer{}
 // NewInformerFunc will be used to craft new single-namespace informers when
 // adding namespaces.
 func NewMultiNamespaceInformer(namespaces NamespaceSet, resync time.Duration, newInformer NewInformerFunc) MultiNamespaceInformer {
-	informer := &multiNamespaceInformer{
+	informer := newMultiNamespaceInformer(namespaces, resync)
+	informer.newInformer = newInformer
+	informer.watchNamespaces()
+
+	return informer
+}
+
+// NewMultiNamespaceInformerWithListerWatcher returns a new cross-namespace
+// informer whose single-namespace informers list and watch objects like the
+// given example object through the cache.ListerWatcher returned by the given
+// NewListerWatcherFunc.  Since their list and watch calls go through the
+// cross-namespace informer, these informers support features that
+// NewMultiNamespaceInformer can't offer, such as restoring from snapshots.
+func NewMultiNamespaceInformerWithListerWatcher(namespaces NamespaceSet, exampleObject runtime.Object, resync time.Duration,
+	indexers cache.Indexers, newListerWatcher NewListerWatcherFunc,
+) MultiNamespaceInformer {
+	informer := newMultiNamespaceInformer(namespaces, resync)
+	informer.exampleObject = exampleObject
+	informer.newListerWatcher = newListerWatcher
+	informer.newInformer = func(namespace string) cache.SharedIndexInformer {
+		lw := &namespaceListerWatcher{
+			ListerWatcher: newListerWatcher(namespace),
+			namespace:     namespace,
+			informer:      informer,
+		}
+
+		return cache.NewSharedIndexInformer(lw, exampleObject, resync, indexers)
+	}
+	informer.watchNamespaces()
+
+	return informer
+}
+
+func newMultiNamespaceInformer(namespaces NamespaceSet, resync time.Duration) *multiNamespaceInformer {
+	return &multiNamespaceInformer{
 		informers:     make(map[string]cache.SharedIndexInformer),
 		stopChans:     make(map[string]chan struct{}),
-		eventHandlers: make([]eventHandlerData, 0),
+		eventHandlers: make([]*eventHandlerData, 0),
 		indexers:      make([]cache.Indexers, 0),
 		namespaces:    namespaces,
 		resyncPeriod:  resync,
-		newInformer:   newInformer,
 
-		registrationHandlers: make(map[string]cache.ResourceEventHandlerRegistration),
+		pausedNamespaces:   sets.NewSet(),
+		degradedNamespaces: sets.NewSet(),
+		pendingNamespaces:  sets.NewSet(),
+		indexMirrors:       make(map[string][]*indexMirror),
+		feeds:              make(map[string][]*namespaceFeed),
 	}
+}
 
-	namespaces.AddHandler(NamespaceSetHandlerFuncs{
-		AddFunc:    informer.AddNamespace,
-		RemoveFunc: informer.RemoveNamespace,
-	})
+// watchNamespaces adds and removes namespaces as the namespace set changes.
+func (i *multiNamespaceInformer) watchNamespaces() {
+	if exclusions, ok := i.namespaces.(ExclusionNamespaceSet); ok {
+		i.watchExclusions(exclusions)
+	}
 
-	return informer
+	i.namespaces.AddHandler(NamespaceSetHandlerFuncs{
+		AddFunc:    i.AddNamespace,
+		RemoveFunc: i.RemoveNamespace,
+	})
 }
 
 // GetController is unimplemented and always returns nil.
@@ -131,6 +406,19 @@ func (i *multiNamespaceInformer) AddNamespace(namespace string) {
 		return
 	}
 
+	informer := i.setupInformer(namespace)
+
+	if i.isRunning() {
+		i.runByPriority(namespace, informer)
+	}
+
+	klog.V(4).Infof("Added informer for namespace: %q", namespace)
+}
+
+// setupInformer creates a new informer for the given namespace with the
+// informer's indexers, event handlers, and other settings.  Any existing
+// informer for the namespace is replaced.  The lock must be held.
+func (i *multiNamespaceInformer) setupInformer(namespace string) cache.SharedIndexInformer {
 	informer := i.newInformer(namespace)
 
 	// Add indexers to the new informer.
@@ -138,16 +426,33 @@ func (i *multiNamespaceInformer) AddNamespace(namespace string) {
 		_ = informer.AddIndexers(idx)
 	}
 
+	if i.transform != nil {
+		if err := informer.SetTransform(i.transform); err != nil {
+			klog.Errorf("Failed to set transform for namespace %q: %v", namespace, err)
+		}
+	}
+
+	// Objects from a replaced informer are listed again by the new one.
+	i.removeFeeds(namespace)
+	i.addDeltaFeeds(namespace)
+
+	for _, m := range i.globalMirrors {
+		if _, err := i.addFeed(namespace, informer, newGlobalIndexFeed(m)); err != nil {
+			klog.Errorf("Failed to add global indexers for namespace %q: %v", namespace, err)
+		}
+	}
+
+	if i.snapshots != nil {
+		if _, err := i.addFeed(namespace, informer, i.snapshots.namespaceHandler(namespace)); err != nil {
+			klog.Errorf("Failed to track namespace %q for snapshots: %v", namespace, err)
+		}
+	}
+
 	// Add event handlers to the new informer.
 	for _, handler := range i.eventHandlers {
-		r, err := informer.AddEventHandlerWithResyncPeriod(
-			handler.handler,
-			handler.resyncPeriod,
-		)
-		if err != nil {
+		if err := i.addNamespaceHandler(handler, namespace, informer); err != nil {
 			klog.Errorf("Failed to add event handler for namespace %q: %v", namespace, err)
 		}
-		i.registrationHandlers[namespace] = r
 	}
 
 	// Add watch error handler.
@@ -158,20 +463,127 @@ func (i *multiNamespaceInformer) AddNamespace(namespace string) {
 		}
 	}
 
-	stopCh := make(chan struct{})
-
 	i.informers[namespace] = informer
-	i.stopChans[namespace] = stopCh
+	i.stopChans[namespace] = make(chan struct{})
 
-	if i.started {
-		go informer.Run(stopCh)
+	// The new informer has all indexers, so no mirrors are needed.  It lists
+	// its namespace again, which checks the budget.
+	delete(i.indexMirrors, namespace)
+
+	if i.budget != nil {
+		i.budget.setMirrors(namespace, 0)
 	}
 
-	klog.V(4).Infof("Added informer for namespace: %q", namespace)
+	return informer
+}
+
+// addNamespaceHandler adds an event handler to the informer for the given
+// namespace.  The lock must be held.
+func (i *multiNamespaceInformer) addNamespaceHandler(d *eventHandlerData, namespace string, informer cache.SharedIndexInformer) error {
+	// Events still held back for a replaced informer are stale, since the new
+	// informer lists everything again.
+	if old, ok := d.namespaces[namespace]; ok && old.throttled != nil {
+		old.throttled.close(false)
+	}
+
+	var (
+		handler   = d.target()
+		throttled *throttledHandler
+	)
+
+	if d.options.throttled() {
+		throttled = newThrottledHandler(handler, d.options.coalesceWindow, &i.wg, d.limiter, d.options.newNamespaceLimiter())
+		handler = throttled
+	}
+
+	h := newNamespaceHandler(namespace, handler, i.pausedNamespaces.Contains(namespace))
+	r, err := informer.AddEventHandlerWithResyncPeriod(h, d.resyncPeriod)
+	d.namespaces[namespace] = &namespaceRegistration{handler: h, throttled: throttled, registration: r}
+
+	return err
+}
+
+// addFeed feeds the events of the given namespace's informer into the given
+// handler.  The lock must be held.
+func (i *multiNamespaceInformer) addFeed(namespace string, informer cache.SharedIndexInformer,
+	handler cache.ResourceEventHandler,
+) (*namespaceFeed, error) {
+	f := &namespaceFeed{handler: handler}
+
+	registration, err := informer.AddEventHandler(f)
+	if err != nil {
+		return nil, err
+	}
+
+	f.registration = registration
+	i.feeds[namespace] = append(i.feeds[namespace], f)
+
+	return f, nil
+}
+
+// removeFeeds closes the given namespace's feeds and removes its objects from
+// the global indexes and snapshot store.  The lock must be held.
+func (i *multiNamespaceInformer) removeFeeds(namespace string) {
+	for _, f := range i.feeds[namespace] {
+		f.close()
+
+		// The feed tracks the keys it added, since the namespace may hold
+		// objects of any namespace when it is metav1.NamespaceAll.
+		if g, ok := f.handler.(*globalIndexFeed); ok {
+			g.remove()
+		}
+	}
+
+	delete(i.feeds, namespace)
+
+	if i.snapshots != nil {
+		i.snapshots.removeNamespace(namespace)
+	}
+}
+
+// runInformer runs the given namespace's informer until it is stopped.  The
+// lock must be held.
+func (i *multiNamespaceInformer) runInformer(namespace string, informer cache.SharedIndexInformer) {
+	stopCh := i.stopChans[namespace]
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+		informer.Run(stopCh)
+	}()
+}
+
+// stopInformer stops the given namespace's informer if it is running.  The
+// lock must be held.
+func (i *multiNamespaceInformer) stopInformer(namespace string) {
+	if stopCh := i.stopChans[namespace]; stopCh != nil {
+		close(stopCh)
+		i.stopChans[namespace] = nil
+	}
 }
 
 // RemoveNamespace stops and deletes the informer for the given namespace.
 func (i *multiNamespaceInformer) RemoveNamespace(namespace string) {
+	deliver, ok := i.removeNamespace(namespace)
+	if !ok {
+		return
+	}
+
+	// Handlers, such as worker pools with full queues, may block on readers
+	// of the informer, so the final events are delivered without the lock.
+	deliver()
+
+	// The budget's callbacks may call into the informer when the namespace
+	// makes room for others, so it is updated without holding the lock.
+	if b := i.getBudget(); b != nil {
+		b.remove(namespace)
+	}
+}
+
+// removeNamespace does the work of RemoveNamespace, returning a function that
+// delivers the namespace's final events.  It returns false if the namespace
+// wasn't tracked.
+func (i *multiNamespaceInformer) removeNamespace(namespace string) (func(), bool) {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
@@ -179,59 +591,233 @@ func (i *multiNamespaceInformer) RemoveNamespace(namespace string) {
 
 	// If there is no informer for this namespace, this is a no-op.
 	if !ok {
-		return
+		return nil, false
 	}
 
-	close(i.stopChans[namespace]) // Stop the informer.
+	i.stopInformer(namespace)
 
-	// Send delete events for everything in the store.
-	for _, obj := range informer.GetStore().List() {
-		for _, h := range i.eventHandlers {
-			h.handler.OnDelete(obj)
+	var (
+		registrations []*namespaceRegistration
+		targets       []cache.ResourceEventHandler
+	)
+
+	for _, h := range i.eventHandlers {
+		if r, ok := h.namespaces[namespace]; ok {
+			registrations = append(registrations, r)
 		}
+
+		targets = append(targets, h.target())
 	}
 
+	objs := informer.GetStore().List()
+
+	deliver := func() {
+		// Deliver anything queued while the namespace was paused, so handlers
+		// don't miss deletions that happened in the meantime.
+		for _, r := range registrations {
+			r.handler.resume()
+
+			if r.throttled != nil {
+				r.throttled.close(true)
+			}
+		}
+
+		// Send delete events for everything in the store.
+		for _, obj := range objs {
+			for _, h := range targets {
+				h.OnDelete(obj)
+			}
+		}
+	}
+
+	for _, h := range i.eventHandlers {
+		delete(h.namespaces, namespace)
+	}
+
+	i.removeFeeds(namespace)
+	i.removeDeltaFeeds(namespace)
+
 	delete(i.stopChans, namespace)
 	delete(i.informers, namespace)
+	delete(i.indexMirrors, namespace)
+	delete(i.degradedNamespaces, namespace)
+	delete(i.pendingNamespaces, namespace)
+
+	if i.watchdog != nil {
+		i.watchdog.remove(namespace)
+	}
 
 	klog.V(4).Infof("Removed informer for namespace: %q", namespace)
+
+	return deliver, true
 }
 
-// Run starts all informers and waits for the stop channel to close.
-func (i *multiNamespaceInformer) Run(stopCh <-chan struct{}) {
-	if i.hasStarted() {
-		klog.Warningf("The multiNamespaceInformer has started, run more than once is not allowed")
-		return
+// PauseNamespace stops event delivery for the given namespace.  Its cache is
+// still kept up to date, so listers keep serving the namespace while it is
+// paused.  Events are queued and coalesced by key until ResumeNamespace is
+// called.  The namespace doesn't need to be tracked yet; if it is added later
+// its handlers will start out paused.
+func (i *multiNamespaceInformer) PauseNamespace(namespace string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.pausedNamespaces.Insert(namespace)
+
+	for _, h := range i.eventHandlers {
+		if r, ok := h.namespaces[namespace]; ok {
+			r.handler.pause()
+		}
 	}
 
-	func() {
+	klog.V(4).Infof("Paused event delivery for namespace: %q", namespace)
+}
+
+// ResumeNamespace delivers the events queued for the given namespace while it
+// was paused and restarts normal delivery.  Queued events are compacted, so
+// each handler sees at most one add, update, or delete per object.  Handlers
+// must not pause or resume the namespace they are being called for.
+func (i *multiNamespaceInformer) ResumeNamespace(namespace string) {
+	handlers := func() []*namespaceHandler {
 		i.lock.Lock()
 		defer i.lock.Unlock()
 
-		for namespace, informer := range i.informers {
-			go informer.Run(i.stopChans[namespace])
+		delete(i.pausedNamespaces, namespace)
+
+		var handlers []*namespaceHandler
+		for _, h := range i.eventHandlers {
+			if r, ok := h.namespaces[namespace]; ok {
+				handlers = append(handlers, r.handler)
+			}
 		}
 
-		i.started = true
+		return handlers
 	}()
 
-	<-stopCh // Block until stopCh is closed.
+	for _, h := range handlers {
+		h.resume()
+	}
+
+	klog.V(4).Infof("Resumed event delivery for namespace: %q", namespace)
+}
 
+// Run starts all informers and waits for the stop channel to close or for Stop
+// to be called.  An informer that has been stopped may be run again, in which
+// case new per-namespace informers are created with the same event handlers,
+// indexers, and other settings.  Event handlers will see the initial list of
+// each namespace again.  Call Wait before running the informer again to make
+// sure no events from the previous run are still being delivered.
+func (i *multiNamespaceInformer) Run(stopCh <-chan struct{}) {
+	runStopCh, ok := i.start()
+	if !ok {
+		klog.Warningf("The multiNamespaceInformer is running, run more than once is not allowed")
+		return
+	}
+
+	select {
+	case <-stopCh:
+	case <-runStopCh:
+	}
+
+	i.Stop()
+}
+
+// start starts all informers, creating new ones if the informer has been run
+// before.  It returns the channel closed by Stop, or false if the informer is
+// already running.
+func (i *multiNamespaceInformer) start() (<-chan struct{}, bool) {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	for namespace := range i.informers {
-		close(i.stopChans[namespace])
+	if i.isRunning() {
+		return nil, false
 	}
 
-	i.stopped = true
+	// Informers can't be run twice, so they are replaced on restart.
+	if i.stopped {
+		for namespace := range i.informers {
+			i.setupInformer(namespace)
+		}
+	}
+
+	for _, h := range i.eventHandlers {
+		if h.pool != nil {
+			h.pool.start(&i.wg)
+		}
+	}
+
+	i.runStopCh = make(chan struct{})
+	i.started = true
+	i.stopped = false
+
+	// Namespaces are started one priority tier at a time, so higher priority
+	// namespaces list and sync first.
+	tiers := i.priorityTiers()
+	for n, tier := range tiers {
+		for _, namespace := range tier {
+			if n == 0 {
+				i.runInformer(namespace, i.informers[namespace])
+			} else {
+				i.pendingNamespaces.Insert(namespace)
+			}
+		}
+	}
+
+	if len(tiers) > 1 {
+		runStopCh := i.runStopCh
+
+		i.wg.Add(1)
+		go func() {
+			defer i.wg.Done()
+			i.startTiers(runStopCh, tiers)
+		}()
+	}
+
+	if i.watchdog != nil {
+		i.runWatchdog()
+	}
+
+	return i.runStopCh, true
 }
 
-func (i *multiNamespaceInformer) hasStarted() bool {
+// Stop stops all per-namespace informers and ends the current run.  It is a
+// no-op if the informer isn't running.  Use Wait to block until the informers'
+// goroutines have exited.
+func (i *multiNamespaceInformer) Stop() {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	return i.started
+	if !i.isRunning() {
+		return
+	}
+
+	for namespace := range i.informers {
+		i.stopInformer(namespace)
+	}
+
+	for _, h := range i.eventHandlers {
+		if h.pool != nil {
+			h.pool.stop()
+		}
+	}
+
+	i.stopDeltaFeeds()
+
+	close(i.runStopCh)
+	i.pendingNamespaces = sets.NewSet()
+	i.stopped = true
+}
+
+// Wait blocks until the goroutines of all per-namespace informers started by
+// the informer have exited, including those of removed namespaces, along with
+// the goroutines delivering events and deltas to its handlers.
+func (i *multiNamespaceInformer) Wait() {
+	i.wg.Wait()
+}
+
+// isRunning returns true if the informer has been started and not stopped
+// since.  The lock must be held.
+func (i *multiNamespaceInformer) isRunning() bool {
+	return i.started && !i.stopped
 }
 
 // AddEventHandler adds the given handler to each namespaced informer.
@@ -246,43 +832,151 @@ func (i *multiNamespaceInformer) AddEventHandler(
 // created later as namespaces are added.
 func (i *multiNamespaceInformer) AddEventHandlerWithResyncPeriod(
 	handler cache.ResourceEventHandler, resyncPeriod time.Duration,
+) (cache.ResourceEventHandlerRegistration, error) {
+	return i.AddEventHandlerWithOptions(handler, resyncPeriod)
+}
+
+// AddEventHandlerWithOptions is like AddEventHandlerWithResyncPeriod, but
+// the given options can coalesce and rate limit the events delivered to the
+// handler.  Without options, events are delivered as they arrive.
+func (i *multiNamespaceInformer) AddEventHandlerWithOptions(
+	handler cache.ResourceEventHandler, resyncPeriod time.Duration, opts ...HandlerOption,
 ) (cache.ResourceEventHandlerRegistration, error) {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	i.eventHandlers = append(i.eventHandlers, eventHandlerData{
-		handler:      handler,
+	d := &eventHandlerData{
+		informer:     i,
+		handler:      &journalHandler{handler: handler, informer: i},
 		resyncPeriod: resyncPeriod,
-	})
+		namespaces:   make(map[string]*namespaceRegistration),
+	}
+
+	for _, opt := range opts {
+		opt(&d.options)
+	}
+
+	d.limiter = d.options.newLimiter()
+
+	if d.options.workers > 0 {
+		d.pool = newWorkerPool(d.handler, d.options.workers, d.options.queueSize)
+
+		if i.isRunning() {
+			d.pool.start(&i.wg)
+		}
+	}
+
+	i.eventHandlers = append(i.eventHandlers, d)
 
 	for ns, informer := range i.informers {
-		r, err := informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
-		if err != nil {
+		if err := i.addNamespaceHandler(d, ns, informer); err != nil {
 			return nil, err
 		}
-		i.registrationHandlers[ns] = r
 	}
-	return i, nil
+
+	return d, nil
 }
 
-// AddIndexers adds the given indexers to each namespaced informer.
+// AddIndexers adds the given indexers to each namespaced informer.  Unlike
+// upstream informers, indexers may be added while the informer is running.
+// The indexes of running namespaces are built from their existing caches
+// before this returns, and the indexers are also added to namespaces added
+// later.  If any namespace fails, the indexers aren't added at all.
+//
+// Upstream indexers can't index objects they already hold, so a running
+// namespace gets a mirror: a second indexer holding the same objects, kept up
+// to date by an event handler.  Each mirror costs a map of keys and the new
+// indexes, but not copies of the objects, and is counted against the budget
+// as another copy of the namespace's objects.  Since the mirror follows the
+// informer's events, its indexes may briefly lag behind the cache.  Mirrors
+// are dropped when the informer is restarted, which builds the indexes into
+// the new informers.
 func (i *multiNamespaceInformer) AddIndexers(indexers cache.Indexers) error {
 	i.lock.Lock()
-	defer i.lock.Unlock()
 
-	i.indexers = append(i.indexers, indexers)
+	if conflicts := i.indexerConflicts(indexers); len(conflicts) > 0 {
+		i.lock.Unlock()
+		return fmt.Errorf("indexer conflict: %v", conflicts)
+	}
 
-	for _, informer := range i.informers {
-		err := informer.AddIndexers(indexers)
-		if err != nil {
+	// Stopped informers are replaced on restart, so they will get the new
+	// indexers then.
+	var mirrored []string
+	if !i.stopped {
+		var err error
+		if mirrored, err = i.addNamespaceIndexers(indexers); err != nil {
+			i.lock.Unlock()
 			return err
 		}
 	}
 
+	i.indexers = append(i.indexers, indexers)
+	b := i.budget
+	i.lock.Unlock()
+
+	// The budget's callbacks may call back into the informer, so mirrored
+	// namespaces are checked against it without the lock held.
+	if b != nil {
+		for _, namespace := range mirrored {
+			b.check(namespace)
+		}
+	}
+
 	return nil
 }
 
-// HasSynced checks if each namespaced informer has synced.
+// addNamespaceIndexers adds the given indexers to the informer of each
+// namespace, mirroring them for running informers, and returns the mirrored
+// namespaces.  If any namespace fails, the others are rolled back.  The lock
+// must be held.
+func (i *multiNamespaceInformer) addNamespaceIndexers(indexers cache.Indexers) ([]string, error) {
+	if !i.isRunning() {
+		var added []string
+		for namespace, informer := range i.informers {
+			if err := informer.AddIndexers(indexers); err != nil {
+				// Informers that haven't run yet are simply replaced.
+				for _, ns := range added {
+					i.setupInformer(ns)
+				}
+
+				return nil, err
+			}
+
+			added = append(added, namespace)
+		}
+
+		return nil, nil
+	}
+
+	mirrors := make(map[string]*indexMirror, len(i.informers))
+	for namespace, informer := range i.informers {
+		m, err := newIndexMirror(informer, indexers)
+		if err != nil {
+			for ns, m := range mirrors {
+				m.close(i.informers[ns])
+			}
+
+			return nil, err
+		}
+
+		mirrors[namespace] = m
+	}
+
+	mirrored := make([]string, 0, len(mirrors))
+	for namespace, m := range mirrors {
+		i.indexMirrors[namespace] = append(i.indexMirrors[namespace], m)
+		mirrored = append(mirrored, namespace)
+
+		if i.budget != nil {
+			i.budget.setMirrors(namespace, len(i.indexMirrors[namespace]))
+		}
+	}
+
+	return mirrored, nil
+}
+
+// HasSynced checks if each namespaced informer has synced.  Namespaces marked
+// degraded with MarkNamespacesDegraded are skipped.
 func (i *multiNamespaceInformer) HasSynced() bool {
 	if !i.namespaces.Initialized() {
 		return false
@@ -291,7 +985,13 @@ func (i *multiNamespaceInformer) HasSynced() bool {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	for _, informer := range i.informers {
+	i.updateDegradedNamespaces()
+
+	for namespace, informer := range i.informers {
+		if i.degradedNamespaces.Contains(namespace) {
+			continue
+		}
+
 		if synced := informer.HasSynced(); !synced {
 			return false
 		}
@@ -306,35 +1006,74 @@ func (i *multiNamespaceInformer) GetIndexers() map[string]cache.Indexer {
 	defer i.lock.Unlock()
 
 	res := make(map[string]cache.Indexer, len(i.informers))
-	for namespace, informer := range i.informers {
-		res[namespace] = informer.GetIndexer()
+	for namespace := range i.informers {
+		res[namespace] = i.namespaceIndexer(namespace)
 	}
 
 	return res
 }
 
-// SetTransform will pass the handler into each individual Informer and return an aggregate error
+// SetTransform will pass the handler into each individual Informer and return an aggregate error.
+// The transform will also be set for any informers created later.
 func (i *multiNamespaceInformer) SetTransform(handler cache.TransformFunc) error {
-	errList := make([]error, len(i.informers))
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.transform = handler
+
+	i.deltaLock.Lock()
+	i.deltaTransform = handler
+	i.deltaLock.Unlock()
+
+	errList := make([]error, 0, len(i.informers))
 	for _, informer := range i.informers {
 		errList = append(errList, informer.SetTransform(handler))
 	}
 	return errors.NewAggregate(errList)
 }
 
-func (i *multiNamespaceInformer) RemoveEventHandler(_ cache.ResourceEventHandlerRegistration) error {
+// RemoveEventHandler removes a handler added with AddEventHandler from each
+// namespaced informer.  The handler won't be added to informers created later.
+func (i *multiNamespaceInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegistration) error {
 	i.lock.Lock()
 	defer i.lock.Unlock()
 
-	for ns, inf := range i.informers {
-		r, found := i.registrationHandlers[ns]
-		if !found {
+	if d, ok := handle.(*deltaHandlerData); ok {
+		return i.removeDeltaHandler(d)
+	}
+
+	d, ok := handle.(*eventHandlerData)
+	if !ok || d.informer != i {
+		return fmt.Errorf("registration %v is not from this informer", handle)
+	}
+
+	for idx, h := range i.eventHandlers {
+		if h == d {
+			i.eventHandlers = append(i.eventHandlers[:idx], i.eventHandlers[idx+1:]...)
+			break
+		}
+	}
+
+	for ns, r := range d.namespaces {
+		if r.throttled != nil {
+			r.throttled.close(false)
+		}
+
+		inf, found := i.informers[ns]
+		if !found || r.registration == nil {
 			continue
 		}
-		if err := inf.RemoveEventHandler(r); err != nil {
+		if err := inf.RemoveEventHandler(r.registration); err != nil {
 			return err
 		}
 	}
+
+	d.namespaces = make(map[string]*namespaceRegistration)
+
+	if d.pool != nil {
+		d.pool.stop()
+	}
+
 	return nil
 }
 
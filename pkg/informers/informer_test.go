@@ -6,6 +6,8 @@ package informers_test
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
@@ -223,8 +225,10 @@ func TestSharedInformerErrorHandling(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Errorf("Timeout waiting for error handler call")
 	}
-	close(stop)
 
+	// The informer is stopped only once ns2's events have been checked.
+	// Stopping it as soon as ns1 fails, as the upstream test does, stops ns2
+	// before it delivers pod2 whenever ns1 fails first.
 	if !ns2Listener.ok() {
 		t.Errorf("%s: expected %v, got %v",
 			ns2Listener.name,
@@ -232,6 +236,8 @@ func TestSharedInformerErrorHandling(t *testing.T) {
 			ns2Listener.receivedItemNames,
 		)
 	}
+
+	close(stop)
 }
 
 func TestMultiNamespaceInformerEventHandlers(t *testing.T) {
@@ -340,6 +346,104 @@ func TestMultiNamespaceInformerEventHandlers(t *testing.T) {
 	}
 }
 
+func TestMultiNamespaceInformerStopAndRestart(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	recorder := &eventRecorder{}
+	registration, err := informer.AddEventHandlerWithResyncPeriod(recorder, 0)
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	recorder.waitFor(t, 1)
+	recorder.reset()
+
+	informer.Stop()
+	informer.Wait()
+
+	if !informer.IsStopped() {
+		t.Fatalf("Expected informer to be stopped")
+	}
+
+	// Changes made while stopped are picked up by the new informers.
+	source.Add(newPod("ns1", "pod2"))
+
+	if registration.HasSynced() {
+		t.Fatalf("Expected handler not to be synced while stopped")
+	}
+
+	go informer.Run(stop)
+
+	// The handler is synced once it has been sent the initial list of the
+	// restarted informers.
+	ctx, cancel := context.WithTimeout(context.TODO(), wait.ForeverTestTimeout)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+		t.Fatalf("Timed out waiting for the restarted informer to sync")
+	}
+
+	waitForKeys(t, informer, "ns1/pod1", "ns1/pod2")
+
+	events := recorder.reset()
+	sort.Strings(events)
+
+	if expected := []string{"add:pod1:1", "add:pod2:2"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", events, expected)
+	}
+
+	informer.Stop()
+	informer.Wait()
+}
+
+func TestMultiNamespaceInformerRemoveEventHandler(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	removed := &eventRecorder{}
+	kept := &eventRecorder{}
+
+	registration, _ := informer.AddEventHandlerWithResyncPeriod(removed, 0)
+	_, _ = informer.AddEventHandlerWithResyncPeriod(kept, 0)
+
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		t.Fatalf("Failed to remove event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	source.Add(newPod("ns1", "pod1"))
+	kept.waitFor(t, 1)
+
+	if events := removed.reset(); len(events) != 0 {
+		t.Errorf("Removed handler received events: %v", events)
+	}
+
+	if err := informer.RemoveEventHandler(mockRegistration{}); err == nil {
+		t.Errorf("Expected error removing a foreign registration")
+	}
+}
+
+type mockRegistration struct{}
+
+func (mockRegistration) HasSynced() bool {
+	return true
+}
+
 func TestMultiNamespaceInformerHasSynced(t *testing.T) {
 	namespaceSet := xnsinformers.NewNamespaceSet()
 	hasSynced := false
@@ -424,3 +528,110 @@ func (m mockInformer) RemoveEventHandler(handle cache.ResourceEventHandlerRegist
 func (m mockInformer) IsStopped() bool {
 	panic("implement me")
 }
+
+func TestMultiNamespaceInformerRemoveNamespaceCallsBack(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	namespaces := xnsinformers.NewNamespaceSet("ns1")
+	informer := xnsinformers.NewMultiNamespaceInformerWithListerWatcher(namespaces, &v1.Pod{}, 0, cache.Indexers{},
+		func(namespace string) cache.ListerWatcher {
+			return source
+		},
+	)
+
+	deleted := make(chan bool, 1)
+
+	// Handlers called for the removal of a namespace may call back into the
+	// namespace set.
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: func(obj interface{}) {
+			deleted <- namespaces.Contains("ns1")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	go namespaces.SetNamespaces([]string{})
+
+	select {
+	case contains := <-deleted:
+		if contains {
+			t.Errorf("Expected ns1 to be removed from the set")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for delete event")
+	}
+
+	namespaces.(xnsinformers.NamespaceSetHandlerRemover).Flush()
+}
+
+func TestMultiNamespaceInformerHasSyncedQueuedNamespaces(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+	}
+
+	sources["ns1"].Add(newPod("ns1", "pod1"))
+	sources["ns2"].Add(newPod("ns2", "pod2"))
+
+	namespaces := xnsinformers.NewUninitializedNamespaceSet()
+
+	// A handler added before the informer's holds up sending the first
+	// change, so the next one is queued behind it.
+	var (
+		once    sync.Once
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	namespaces.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			once.Do(func() {
+				close(started)
+				<-release
+			})
+		},
+	})
+
+	informer := xnsinformers.NewMultiNamespaceInformerWithListerWatcher(namespaces, &v1.Pod{}, 0, cache.Indexers{},
+		func(namespace string) cache.ListerWatcher {
+			return sources[namespace]
+		},
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+
+	go namespaces.SetNamespaces([]string{"ns1"})
+	<-started
+	namespaces.SetNamespaces([]string{"ns1", "ns2"})
+
+	// Neither change has reached the informer yet.
+	if namespaces.Initialized() {
+		t.Errorf("Expected namespace set not to be initialized before its handlers are sent the changes")
+	}
+
+	if informer.HasSynced() {
+		t.Errorf("Expected informer not to be synced before it is sent its namespaces")
+	}
+
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), wait.ForeverTestTimeout)
+	defer cancel()
+
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		t.Fatalf("Timed out waiting for informer to sync")
+	}
+
+	waitForKeys(t, informer, "ns1/pod1", "ns2/pod2")
+}
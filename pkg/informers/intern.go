@@ -0,0 +1,138 @@
+package informers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxInternLength is the length of the longest string an InternTable interns.
+// Longer strings, such as large annotation values, are rarely duplicated.
+const maxInternLength = 256
+
+// InternTable holds a single copy of commonly repeated strings, so objects in
+// different caches can share them instead of each holding its own copy.  The
+// table is bounded, and strings that haven't been interned for a while are
+// evicted to make room for new ones.  It is safe for concurrent use.
+type InternTable struct {
+	generationSize int
+
+	// Strings are added to current, and once it is full it replaces
+	// previous, dropping the strings that weren't interned again since the
+	// last replacement.  Strings found in previous are moved to current.
+	lock     sync.RWMutex
+	current  map[string]string
+	previous map[string]string
+}
+
+// NewInternTable returns a new InternTable holding up to maxEntries strings,
+// and at least two.
+func NewInternTable(maxEntries int) *InternTable {
+	generationSize := maxEntries / 2
+	if generationSize < 1 {
+		generationSize = 1
+	}
+
+	return &InternTable{
+		generationSize: generationSize,
+		current:        make(map[string]string),
+	}
+}
+
+// Intern returns the table's copy of the given string, adding it to the table
+// if it isn't there yet.
+func (t *InternTable) Intern(s string) string {
+	if s == "" || len(s) > maxInternLength {
+		return s
+	}
+
+	t.lock.RLock()
+	interned, ok := t.current[s]
+	t.lock.RUnlock()
+
+	if ok {
+		return interned
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if interned, ok := t.current[s]; ok {
+		return interned
+	}
+
+	if interned, ok := t.previous[s]; ok {
+		delete(t.previous, s)
+		s = interned
+	}
+
+	if len(t.current) >= t.generationSize {
+		t.previous = t.current
+		t.current = make(map[string]string, t.generationSize)
+	}
+
+	t.current[s] = s
+
+	return s
+}
+
+// Len returns the number of strings in the table.
+func (t *InternTable) Len() int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return len(t.current) + len(t.previous)
+}
+
+// internMap returns a copy of the given map with interned keys and values.
+func (t *InternTable) internMap(m map[string]string, values bool) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		if values {
+			v = t.Intern(v)
+		}
+		res[t.Intern(k)] = v
+	}
+
+	return res
+}
+
+// InternMetadataTransform returns a cache.TransformFunc that interns the
+// namespace, labels, annotation keys, and owner reference kinds and API
+// versions of objects through the given table.  Sharing one table between
+// informers, for example with SetTransform or a factory's WithTransform
+// option, lets every namespace's cache share those strings.  Annotation values
+// aren't interned, since they are rarely repeated.  The transform must be set
+// before the informer is started.
+func InternMetadataTransform(table *InternTable) cache.TransformFunc {
+	return func(obj interface{}) (interface{}, error) {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			// Objects without metadata, such as tombstones, are left alone.
+			return obj, nil
+		}
+
+		o.SetNamespace(table.Intern(o.GetNamespace()))
+		o.SetLabels(table.internMap(o.GetLabels(), true))
+		o.SetAnnotations(table.internMap(o.GetAnnotations(), false))
+
+		if refs := o.GetOwnerReferences(); len(refs) > 0 {
+			interned := make([]metav1.OwnerReference, len(refs))
+			for n, ref := range refs {
+				ref.APIVersion = table.Intern(ref.APIVersion)
+				ref.Kind = table.Intern(ref.Kind)
+				interned[n] = ref
+			}
+
+			o.SetOwnerReferences(interned)
+		}
+
+		return obj, nil
+	}
+}
@@ -0,0 +1,183 @@
+package informers_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// newDecodedPod returns a pod with typical metadata whose strings are all
+// separately allocated, like those of a pod decoded from an API response.
+func newDecodedPod(namespace, name string) *v1.Pod {
+	clone := strings.Clone
+
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: clone(namespace),
+		Name:      name,
+		Labels: map[string]string{
+			clone("app.kubernetes.io/name"):       clone("productpage"),
+			clone("app.kubernetes.io/managed-by"): clone("helm"),
+			clone("pod-template-hash"):            clone("7d4f8b9c5"),
+			clone("security.istio.io/tlsMode"):    clone("istio"),
+			clone("version"):                      clone("v1"),
+		},
+		Annotations: map[string]string{
+			clone("kubectl.kubernetes.io/default-container"): clone("productpage"),
+			clone("sidecar.istio.io/status"):                 clone(`{"initContainers":["istio-init"]}`),
+		},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: clone("apps/v1"),
+			Kind:       clone("ReplicaSet"),
+			Name:       clone("productpage-v1-7d4f8b9c5"),
+		}},
+	}}
+}
+
+func sameString(a, b string) bool {
+	return unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func TestInternTable(t *testing.T) {
+	table := xnsinformers.NewInternTable(2)
+
+	a := table.Intern(strings.Clone("a"))
+	if s := table.Intern(strings.Clone("a")); !sameString(a, s) {
+		t.Errorf("Expected interned strings to share data")
+	}
+
+	b := table.Intern(strings.Clone("b"))
+
+	// Interning a again keeps it, so b is the one evicted to make room for c.
+	if s := table.Intern(strings.Clone("a")); !sameString(a, s) {
+		t.Errorf("Expected a to still be interned")
+	}
+
+	c := table.Intern(strings.Clone("c"))
+
+	if n := table.Len(); n != 2 {
+		t.Errorf("Expected table to be bounded to 2 strings, got %d", n)
+	}
+
+	if s := table.Intern(strings.Clone("c")); !sameString(c, s) {
+		t.Errorf("Expected new strings to be interned once the table is full")
+	}
+
+	if s := table.Intern(strings.Clone("b")); sameString(b, s) {
+		t.Errorf("Expected b to have been evicted")
+	}
+
+	long := strings.Repeat("x", 1024)
+	if table.Intern(long); table.Len() != 2 {
+		t.Errorf("Expected long strings not to be interned")
+	}
+}
+
+func TestInternMetadataTransform(t *testing.T) {
+	table := xnsinformers.NewInternTable(1000)
+	transform := xnsinformers.InternMetadataTransform(table)
+
+	obj1, err := transform(newDecodedPod("ns1", "pod1"))
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+
+	obj2, err := transform(newDecodedPod("ns1", "pod2"))
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+
+	pod1, pod2 := obj1.(*v1.Pod), obj2.(*v1.Pod)
+
+	if !sameString(pod1.Namespace, pod2.Namespace) {
+		t.Errorf("Expected namespaces to be interned")
+	}
+
+	if !sameString(pod1.Labels["version"], pod2.Labels["version"]) {
+		t.Errorf("Expected label values to be interned")
+	}
+
+	if !sameString(pod1.OwnerReferences[0].Kind, pod2.OwnerReferences[0].Kind) {
+		t.Errorf("Expected owner reference kinds to be interned")
+	}
+
+	if pod1.Labels["app.kubernetes.io/name"] != "productpage" || len(pod1.Annotations) != 2 {
+		t.Errorf("Unexpected metadata after transform: %+v", pod1.ObjectMeta)
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetNamespace("ns1")
+	u.SetLabels(map[string]string{"version": "v1"})
+
+	if _, err := transform(u); err != nil {
+		t.Fatalf("Transform failed for unstructured object: %v", err)
+	}
+
+	if u.GetLabels()["version"] != "v1" {
+		t.Errorf("Unexpected labels after transform: %v", u.GetLabels())
+	}
+
+	// Objects without metadata are left alone.
+	if obj, err := transform("not an object"); err != nil || obj != "not an object" {
+		t.Errorf("Expected object without metadata to be returned as is, got %v, %v", obj, err)
+	}
+}
+
+func BenchmarkInternMetadataTransform(b *testing.B) {
+	transform := xnsinformers.InternMetadataTransform(xnsinformers.NewInternTable(10000))
+
+	pods := make([]*v1.Pod, b.N)
+	for n := range pods {
+		pods[n] = newDecodedPod(fmt.Sprintf("ns%d", n%100), fmt.Sprintf("pod%d", n))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		_, _ = transform(pods[n])
+	}
+}
+
+// BenchmarkInternMemory reports the heap retained by pods spread across 100
+// namespaces with and without interning.
+func BenchmarkInternMemory(b *testing.B) {
+	const podCount = 10000
+
+	for _, interned := range []bool{false, true} {
+		b.Run(fmt.Sprintf("interned=%t", interned), func(b *testing.B) {
+			var retained uint64
+
+			for n := 0; n < b.N; n++ {
+				transform := xnsinformers.InternMetadataTransform(xnsinformers.NewInternTable(10000))
+
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				pods := make([]interface{}, podCount)
+				for n := range pods {
+					pods[n] = newDecodedPod(fmt.Sprintf("ns%d", n%100), fmt.Sprintf("pod%d", n))
+
+					if interned {
+						pods[n], _ = transform(pods[n])
+					}
+				}
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(pods)
+
+				retained += after.HeapAlloc - before.HeapAlloc
+			}
+
+			b.ReportMetric(float64(retained)/float64(b.N*podCount), "retained-B/pod")
+		})
+	}
+}
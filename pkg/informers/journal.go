@@ -0,0 +1,165 @@
+package informers
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+// JournalEntry records the delivery of a single event to an event handler.
+type JournalEntry struct {
+	// Time is when the handler was called.
+	Time time.Time `json:"time"`
+
+	// Type is "add", "update", or "delete".
+	Type string `json:"type"`
+
+	Namespace       string `json:"namespace"`
+	Key             string `json:"key"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+
+	// InInitialList is true for adds from an informer's initial list.
+	InInitialList bool `json:"inInitialList,omitempty"`
+
+	// Latency is how long the handler took to handle the event.
+	Latency time.Duration `json:"latency"`
+}
+
+// eventJournal is a bounded ring buffer of journal entries.
+type eventJournal struct {
+	lock    sync.Mutex
+	entries []JournalEntry
+	next    int
+	full    bool
+}
+
+func newEventJournal(size int) *eventJournal {
+	return &eventJournal{entries: make([]JournalEntry, size)}
+}
+
+// record adds an entry, replacing the oldest one if the journal is full.
+func (j *eventJournal) record(e JournalEntry) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.entries[j.next] = e
+	j.next = (j.next + 1) % len(j.entries)
+
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// list returns the journal's entries, oldest first.
+func (j *eventJournal) list() []JournalEntry {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if !j.full {
+		return append([]JournalEntry(nil), j.entries[:j.next]...)
+	}
+
+	res := make([]JournalEntry, 0, len(j.entries))
+	res = append(res, j.entries[j.next:]...)
+
+	return append(res, j.entries[:j.next]...)
+}
+
+// journalHandler wraps an event handler added to the informer, recording each
+// delivery in the informer's journal while it has one.
+type journalHandler struct {
+	handler  cache.ResourceEventHandler
+	informer *multiNamespaceInformer
+}
+
+var _ cache.ResourceEventHandler = &journalHandler{}
+
+func (h *journalHandler) OnAdd(obj interface{}, isInInitialList bool) {
+	h.deliver("add", obj, isInInitialList, func() {
+		h.handler.OnAdd(obj, isInInitialList)
+	})
+}
+
+func (h *journalHandler) OnUpdate(oldObj, newObj interface{}) {
+	h.deliver("update", newObj, false, func() {
+		h.handler.OnUpdate(oldObj, newObj)
+	})
+}
+
+func (h *journalHandler) OnDelete(obj interface{}) {
+	h.deliver("delete", obj, false, func() {
+		h.handler.OnDelete(obj)
+	})
+}
+
+func (h *journalHandler) deliver(eventType string, obj interface{}, isInInitialList bool, deliver func()) {
+	j := h.informer.journal.Load()
+	if j == nil {
+		deliver()
+		return
+	}
+
+	start := time.Now()
+	deliver()
+
+	e := JournalEntry{
+		Time:          start,
+		Type:          eventType,
+		InInitialList: isInInitialList,
+		Latency:       time.Since(start),
+	}
+
+	e.Key, _ = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	if o, err := meta.Accessor(obj); err == nil {
+		e.Namespace = o.GetNamespace()
+		e.ResourceVersion = o.GetResourceVersion()
+	}
+
+	j.record(e)
+}
+
+// EnableJournal starts recording the events delivered to the informer's event
+// handlers in a journal holding the given number of most recent entries.  Each
+// delivery to a handler is a separate entry.  Calling it again replaces the
+// journal, and a size of zero or less disables it.
+func (i *multiNamespaceInformer) EnableJournal(size int) {
+	if size <= 0 {
+		i.journal.Store(nil)
+		return
+	}
+
+	i.journal.Store(newEventJournal(size))
+}
+
+// Journal returns the entries in the informer's journal, oldest first, or nil
+// if it has no journal.
+func (i *multiNamespaceInformer) Journal() []JournalEntry {
+	if j := i.journal.Load(); j != nil {
+		return j.list()
+	}
+
+	return nil
+}
+
+// DumpJournal writes the entries in the informer's journal to the given writer
+// as JSON lines, oldest first.
+func (i *multiNamespaceInformer) DumpJournal(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	for _, e := range i.Journal() {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package informers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiNamespaceInformerJournal(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+	informer.EnableJournal(3)
+
+	if _, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{}); err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	source.Add(newPod("ns1", "pod2"))
+	source.Modify(newPod("ns1", "pod2"))
+	source.Delete(newPod("ns1", "pod1"))
+
+	var journal []xnsinformers.JournalEntry
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		journal = informer.Journal()
+		return len(journal) == 3 && journal[2].Type == "delete", nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for journal entries, got: %+v", journal)
+	}
+
+	// The journal only keeps the most recent entries, oldest first.
+	expected := []struct{ eventType, key string }{
+		{"add", "ns1/pod2"},
+		{"update", "ns1/pod2"},
+		{"delete", "ns1/pod1"},
+	}
+
+	for n, e := range expected {
+		got := journal[n]
+		if got.Type != e.eventType || got.Key != e.key || got.Namespace != "ns1" || got.ResourceVersion == "" {
+			t.Errorf("Unexpected journal entry %d: %+v", n, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := informer.DumpJournal(&buf); err != nil {
+		t.Fatalf("Failed to dump journal: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 JSON lines, got: %q", buf.String())
+	}
+
+	var entry xnsinformers.JournalEntry
+	if err := json.Unmarshal([]byte(lines[2]), &entry); err != nil {
+		t.Fatalf("Failed to decode journal entry: %v", err)
+	}
+
+	if entry.Type != "delete" || entry.Key != "ns1/pod1" {
+		t.Errorf("Unexpected decoded journal entry: %+v", entry)
+	}
+
+	informer.EnableJournal(0)
+
+	if journal := informer.Journal(); journal != nil {
+		t.Errorf("Expected no journal once disabled, got: %+v", journal)
+	}
+}
@@ -0,0 +1,184 @@
+package informers
+
+import (
+	"sync"
+	"sync/atomic"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// namespaceListerWatcher wraps the cache.ListerWatcher used by the informer for
+// a single namespace, giving the cross-namespace informer a hook into its list
+// and watch calls.
+type namespaceListerWatcher struct {
+	cache.ListerWatcher
+	namespace string
+	informer  *multiNamespaceInformer
+
+	// exclusionGeneration is the generation of the exclusions the last list
+	// was filtered with.
+	exclusionGeneration atomic.Int64
+}
+
+var _ cache.ListerWatcher = &namespaceListerWatcher{}
+
+// List serves the first list for a namespace from a restored snapshot if there
+// is one.  The reflector will then watch from the snapshot's resource version,
+// and falls back to a full list if that version is too old.  Objects in
+// excluded namespaces are dropped.  Lists are counted against the informer's
+// budget, if it has one, and feed its delta handlers.
+func (lw *namespaceListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	list := lw.informer.takeRestoredList(lw.namespace)
+
+	if list == nil {
+		var err error
+		if list, err = lw.ListerWatcher.List(options); err != nil {
+			return nil, err
+		}
+
+		if d := lw.informer.getWatchdog(); d != nil {
+			d.touch(lw.namespace)
+		}
+	}
+
+	lw.exclusionGeneration.Store(lw.informer.getExclusionGeneration())
+
+	list, err := lw.informer.filterExcluded(list)
+	if err != nil {
+		return nil, err
+	}
+
+	if b := lw.informer.getBudget(); b != nil {
+		if list, err = b.list(lw.namespace, options, list); err != nil {
+			return nil, err
+		}
+	}
+
+	lw.informer.listDeltas(lw.namespace, options, list)
+
+	return list, nil
+}
+
+// Watch starts a watch, which drops events in excluded namespaces, is tracked
+// by the informer's watchdog and budget if it has them, and feeds its delta
+// handlers.
+func (lw *namespaceListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	w, err := lw.ListerWatcher.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+
+	w = lw.informer.excludeWatch(w, lw.exclusionGeneration.Load())
+
+	if b := lw.informer.getBudget(); b != nil {
+		w = b.track(lw.namespace, w)
+	}
+
+	w = lw.informer.watchDeltas(lw.namespace, w)
+
+	if d := lw.informer.getWatchdog(); d != nil {
+		return d.track(lw.namespace, w), nil
+	}
+
+	return w, nil
+}
+
+// expiringWatch passes through the events of a watch that its filter keeps,
+// and calls its untrack function once it ends.  When expired, it ends with an
+// expired error, so the reflector relists instead of simply watching again.
+// It is used by the informer's hooks that need to make a namespace relist.
+type expiringWatch struct {
+	watch.Interface
+	filter  func(e watch.Event) bool
+	reason  string
+	untrack func(w *expiringWatch)
+
+	result     chan watch.Event
+	expired    chan struct{}
+	expireOnce sync.Once
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+// newExpiringWatch returns a new expiringWatch for the given watch, which ends
+// with the given reason when expired.  A nil filter keeps every event, and a
+// nil untrack function is ignored.  The watch must be started with start.
+func newExpiringWatch(w watch.Interface, reason string, filter func(e watch.Event) bool,
+	untrack func(w *expiringWatch),
+) *expiringWatch {
+	return &expiringWatch{
+		Interface: w,
+		filter:    filter,
+		reason:    reason,
+		untrack:   untrack,
+		result:    make(chan watch.Event),
+		expired:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (w *expiringWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *expiringWatch) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.Interface.Stop()
+	})
+}
+
+// expire ends the watch with an expired error.
+func (w *expiringWatch) expire() {
+	w.expireOnce.Do(func() {
+		close(w.expired)
+	})
+}
+
+// start starts passing through events.
+func (w *expiringWatch) start() {
+	go w.run()
+}
+
+func (w *expiringWatch) run() {
+	defer close(w.result)
+
+	if w.untrack != nil {
+		defer w.untrack(w)
+	}
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-w.expired:
+			w.Interface.Stop()
+
+			err := apierrors.NewResourceExpired(w.reason)
+			select {
+			case w.result <- watch.Event{Type: watch.Error, Object: &err.ErrStatus}:
+			case <-w.done:
+			}
+
+			return
+		case e, ok := <-w.Interface.ResultChan():
+			if !ok {
+				return
+			}
+
+			if w.filter != nil && !w.filter(e) {
+				continue
+			}
+
+			select {
+			case w.result <- e:
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
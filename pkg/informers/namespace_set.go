@@ -1,8 +1,11 @@
 package informers
 
 import (
+	"fmt"
+	"reflect"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/maistra/xns-informer/pkg/internal/sets"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,9 +38,24 @@ func (h NamespaceSetHandlerFuncs) OnRemove(namespace string) {
 	}
 }
 
+// namespaceSetBatchHandler is implemented by handlers that act on the net
+// result of a change rather than on each event.  Sets built on namespaceSet
+// call onBatch after sending the events of each change, and after the initial
+// events sent by AddHandler, with whether the set is initialized.
+type namespaceSetBatchHandler interface {
+	onBatch(initialized bool)
+}
+
 // NamespaceSet represents a dynamic set of namespaces.  The set can be updated
 // with SetNamespaces, and handlers can be added with AddHandler that will
-// respond to addition or removal of individual namespaces.
+// respond to addition or removal of individual namespaces.  Handlers are
+// called in order, without the set locked, so they may call back into it.
+//
+// Sets derived from something else, such as watched objects, access reviews,
+// or other sets, ignore SetNamespaces and log an error, since their
+// namespaces would be overwritten by the next change to their source.
+// PatternNamespaceSet is the exception: its SetNamespaces replaces the
+// entries its namespaces are derived from.
 type NamespaceSet interface {
 	// Initialized returns true if SetNamespaces() has been called at least once
 	Initialized() bool
@@ -47,10 +65,107 @@ type NamespaceSet interface {
 	List() []string
 }
 
+// NamespaceSetHandlerRemover is implemented by namespace sets whose handlers
+// can be removed, such as those returned by this package.  Callers check for
+// it with a type assertion.
+type NamespaceSetHandlerRemover interface {
+	// RemoveHandler removes a handler added with AddHandler.  It is sent no
+	// further events, though a call to it already in progress may still be
+	// running when this returns.
+	RemoveHandler(handler NamespaceSetHandler) error
+
+	// Flush waits until every handler has been sent the events of all
+	// changes made so far.  It must not be called from a handler.
+	Flush()
+}
+
+// PrioritizedNamespaceSet is a NamespaceSet whose namespaces carry a priority.
+// Namespaces with higher priorities are handed to handlers first, and informers
+// start, list, and sync them before the rest, whether they are there when the
+// informer is run, or restarted, or are added later.  Periodic resyncs are run
+// by each namespace's informer on its own schedule, so they aren't ordered.
+type PrioritizedNamespaceSet interface {
+	NamespaceSet
+
+	// Priority returns the priority of the given namespace, which is zero
+	// unless set otherwise.
+	Priority(namespace string) int
+
+	// SetPriorities replaces the namespace priorities.  Namespaces that
+	// aren't in the map have priority zero.
+	SetPriorities(priorities map[string]int)
+}
+
 type namespaceSet struct {
 	lock       sync.Mutex
 	namespaces sets.Set
-	handlers   []NamespaceSetHandler
+	handlers   []*namespaceSetHandlerEntry
+
+	// changes holds the changes waiting to be sent to handlers.  They are
+	// sent by whichever caller finds no one else sending them, and flushed
+	// is signaled once they all have been.
+	changes     []*namespaceSetChange
+	dispatching bool
+	flushed     *sync.Cond
+
+	// initialized is whether the set was initialized by the last change
+	// sent to handlers.
+	initialized bool
+
+	// version is the version of the namespaces last set by setNamespacesAt.
+	version uint64
+
+	// priorities has its own lock, so priorities may be looked up while the
+	// main lock is held.
+	priorityLock sync.RWMutex
+	priorities   map[string]int
+}
+
+var (
+	_ PrioritizedNamespaceSet    = &namespaceSet{}
+	_ NamespaceSetHandlerRemover = &namespaceSet{}
+)
+
+// namespaceSetHandlerEntry is a handler added to a namespace set.
+type namespaceSetHandlerEntry struct {
+	handler NamespaceSetHandler
+	removed atomic.Bool
+}
+
+// namespaceSetChange holds the events of a single change to a namespace set,
+// and the handlers to send them to.
+type namespaceSetChange struct {
+	handlers    []*namespaceSetHandlerEntry
+	removes     []string
+	adds        []string
+	initialized bool
+}
+
+// send sends the change's events to its handlers that haven't been removed.
+func (c *namespaceSetChange) send() {
+	for _, namespace := range c.removes {
+		klog.V(2).Infof("Calling remove funcs for: %q", namespace)
+		for _, h := range c.handlers {
+			if !h.removed.Load() {
+				h.handler.OnRemove(namespace)
+			}
+		}
+	}
+
+	for _, namespace := range c.adds {
+		klog.V(2).Infof("Calling add funcs for: %q", namespace)
+		for _, h := range c.handlers {
+			if !h.removed.Load() {
+				h.handler.OnAdd(namespace)
+			}
+		}
+	}
+
+	for _, h := range c.handlers {
+		if b, ok := h.handler.(namespaceSetBatchHandler); ok && !h.removed.Load() {
+			b.onBatch(c.initialized)
+		}
+	}
 }
 
 // NewNamespaceSet returns a new NamespaceSet tracking the given namespaces.
@@ -61,6 +176,22 @@ func NewNamespaceSet(namespaces ...string) NamespaceSet {
 	return n
 }
 
+// NewPrioritizedNamespaceSet returns a new PrioritizedNamespaceSet tracking the
+// namespaces in the given map with their priorities.
+func NewPrioritizedNamespaceSet(priorities map[string]int) PrioritizedNamespaceSet {
+	n := &namespaceSet{}
+	n.SetPriorities(priorities)
+
+	namespaces := make([]string, 0, len(priorities))
+	for namespace := range priorities {
+		namespaces = append(namespaces, namespace)
+	}
+
+	n.SetNamespaces(namespaces)
+
+	return n
+}
+
 // NewUninitializedNamespaceSet returns a new uninitialized NamespaceSet
 func NewUninitializedNamespaceSet() NamespaceSet {
 	n := &namespaceSet{}
@@ -85,19 +216,49 @@ func (n *namespaceSet) List() []string {
 	return namespaces
 }
 
-// Initialized returns true after SetNamespaces is called at least once.
+// Initialized returns true after SetNamespaces is called at least once, and
+// its changes have been sent to the handlers.
 func (n *namespaceSet) Initialized() bool {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
-	return n.namespaces != nil
+	return n.initialized
 }
 
-// SetNamespaces replaces the set of namespaces.
+// SetNamespaces replaces the set of namespaces.  The handlers are sent the
+// changes before this returns, unless another caller is already sending
+// changes, in which case they are sent by that caller.
 func (n *namespaceSet) SetNamespaces(namespaces []string) {
 	n.lock.Lock()
-	defer n.lock.Unlock()
+	n.setNamespaces(namespaces)
+	n.lock.Unlock()
+
+	n.dispatch()
+}
+
+// setNamespacesAt replaces the set of namespaces like SetNamespaces, unless
+// namespaces of a later version have already been set.  Sets derived from
+// others compute their namespaces under their own lock, and set them once it
+// is released so their handlers may call back into them; the version keeps
+// concurrent updates from being set out of order.  Version zero is never set.
+func (n *namespaceSet) setNamespacesAt(namespaces []string, version uint64) {
+	n.lock.Lock()
+
+	if version <= n.version {
+		n.lock.Unlock()
+		return
+	}
+
+	n.version = version
+	n.setNamespaces(namespaces)
+	n.lock.Unlock()
+
+	n.dispatch()
+}
 
+// setNamespaces queues the changes replacing the set of namespaces.  The lock
+// must be held.
+func (n *namespaceSet) setNamespaces(namespaces []string) {
 	var newNamespaceSet sets.Set
 	if namespaces == nil {
 		newNamespaceSet = nil
@@ -113,33 +274,139 @@ func (n *namespaceSet) SetNamespaces(namespaces []string) {
 
 	klog.V(2).Infof("SetNamespaces: %q", newNamespaceSet.UnsortedList())
 
-	// Call OnRemove handlers.
-	for namespace := range n.namespaces.Difference(newNamespaceSet) {
-		klog.V(2).Infof("Calling remove funcs for: %q", namespace)
-		for _, h := range n.handlers {
-			h.OnRemove(namespace)
-		}
+	removes := n.namespaces.Difference(newNamespaceSet).UnsortedList()
+	sort.Strings(removes)
+
+	n.changes = append(n.changes, &namespaceSetChange{
+		handlers:    append([]*namespaceSetHandlerEntry(nil), n.handlers...),
+		removes:     removes,
+		adds:        n.byPriority(newNamespaceSet.Difference(n.namespaces)),
+		initialized: newNamespaceSet != nil,
+	})
+
+	n.namespaces = newNamespaceSet
+}
+
+// AddHandler adds a handler for add and remove events.  The handler is first
+// sent an add event for each namespace in the set, after any changes already
+// waiting to be sent to other handlers.
+func (n *namespaceSet) AddHandler(handler NamespaceSetHandler) {
+	n.lock.Lock()
+
+	entry := &namespaceSetHandlerEntry{handler: handler}
+	n.handlers = append(n.handlers, entry)
+
+	n.changes = append(n.changes, &namespaceSetChange{
+		handlers:    []*namespaceSetHandlerEntry{entry},
+		adds:        n.byPriority(n.namespaces),
+		initialized: n.namespaces != nil,
+	})
+
+	n.lock.Unlock()
+
+	n.dispatch()
+}
+
+// RemoveHandler removes the given handler, which must be of a comparable type,
+// such as a pointer.  An error is returned if it isn't one of the handlers.
+func (n *namespaceSet) RemoveHandler(handler NamespaceSetHandler) error {
+	if handler == nil || !reflect.TypeOf(handler).Comparable() {
+		return fmt.Errorf("handler of type %T can't be removed", handler)
 	}
 
-	// Call OnAdd handlers.
-	for namespace := range newNamespaceSet.Difference(n.namespaces) {
-		klog.V(2).Infof("Calling add funcs for: %q", namespace)
-		for _, h := range n.handlers {
-			h.OnAdd(namespace)
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	for idx, h := range n.handlers {
+		if h.handler == handler {
+			h.removed.Store(true)
+			n.handlers = append(n.handlers[:idx], n.handlers[idx+1:]...)
+			return nil
 		}
 	}
 
-	n.namespaces = newNamespaceSet
+	return fmt.Errorf("handler %v not found", handler)
 }
 
-// AddHandler adds a handler for add and remove events.
-func (n *namespaceSet) AddHandler(handler NamespaceSetHandler) {
+// Flush waits until the changes made so far have been sent to every handler.
+func (n *namespaceSet) Flush() {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
-	n.handlers = append(n.handlers, handler)
+	for n.dispatching || len(n.changes) > 0 {
+		if n.flushed == nil {
+			n.flushed = sync.NewCond(&n.lock)
+		}
 
-	for ns := range n.namespaces {
-		handler.OnAdd(ns)
+		n.flushed.Wait()
 	}
 }
+
+// dispatch sends the waiting changes to the handlers, in order and without the
+// lock held, unless another caller is already sending them.
+func (n *namespaceSet) dispatch() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.dispatching {
+		return
+	}
+
+	n.dispatching = true
+
+	for len(n.changes) > 0 {
+		c := n.changes[0]
+		n.changes = n.changes[1:]
+
+		n.lock.Unlock()
+		c.send()
+		n.lock.Lock()
+
+		n.initialized = c.initialized
+	}
+
+	n.dispatching = false
+
+	if n.flushed != nil {
+		n.flushed.Broadcast()
+	}
+}
+
+// Priority returns the priority of the given namespace.
+func (n *namespaceSet) Priority(namespace string) int {
+	n.priorityLock.RLock()
+	defer n.priorityLock.RUnlock()
+
+	return n.priorities[namespace]
+}
+
+// SetPriorities replaces the namespace priorities.  This only affects the
+// order in which namespaces are handled from now on.
+func (n *namespaceSet) SetPriorities(priorities map[string]int) {
+	n.priorityLock.Lock()
+	defer n.priorityLock.Unlock()
+
+	n.priorities = make(map[string]int, len(priorities))
+	for namespace, priority := range priorities {
+		n.priorities[namespace] = priority
+	}
+}
+
+// byPriority returns the given namespaces sorted by descending priority, then
+// by name.  The lock must be held.
+func (n *namespaceSet) byPriority(namespaces sets.Set) []string {
+	n.priorityLock.RLock()
+	defer n.priorityLock.RUnlock()
+
+	res := namespaces.UnsortedList()
+
+	sort.Slice(res, func(a, b int) bool {
+		pa, pb := n.priorities[res[a]], n.priorities[res[b]]
+		if pa != pb {
+			return pa > pb
+		}
+		return res[a] < res[b]
+	})
+
+	return res
+}
@@ -0,0 +1,370 @@
+package informers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// accessReviewWorkers is the number of access reviews run concurrently.
+const accessReviewWorkers = 4
+
+// AccessReviewConfig configures when an AccessReviewNamespaceSet checks the
+// namespaces again.
+type AccessReviewConfig struct {
+	// RecheckPeriod is how often every namespace is checked again.  Zero
+	// disables periodic checks.
+	RecheckPeriod time.Duration
+
+	// RoleBindings is an optional RoleBinding informer, run by the caller.
+	// A change to a RoleBinding checks its namespace again.
+	RoleBindings cache.SharedIndexInformer
+}
+
+// AccessReviewNamespaceSet is a NamespaceSet holding the namespaces of another
+// set in which the client is allowed to list and watch a resource, as checked
+// with SelfSubjectAccessReviews.  It is uninitialized until every namespace of
+// the other set has been checked.  Calls to SetNamespaces are ignored.
+type AccessReviewNamespaceSet interface {
+	NamespaceSet
+
+	// Run checks the namespaces and keeps the set, and every view returned
+	// by ForResource, up to date until the stop channel is closed.
+	Run(stopCh <-chan struct{})
+
+	// ForResource returns a view holding the namespaces in which the given
+	// resource may be listed and watched.  Views share the namespaces being
+	// checked and when they are checked again.
+	ForResource(gvr schema.GroupVersionResource) NamespaceSet
+}
+
+// accessReviewKey identifies a single access check in the queue.
+type accessReviewKey struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+// accessReviewer checks access to resources in the namespaces of a candidate
+// set and caches the results in the views for each resource.
+type accessReviewer struct {
+	client     kubernetes.Interface
+	candidates NamespaceSet
+	config     AccessReviewConfig
+	queue      workqueue.RateLimitingInterface
+
+	// views has its own lock, so the handler on the candidates may queue
+	// checks while the main lock is held to read the candidates.
+	viewsLock sync.RWMutex
+	views     map[schema.GroupVersionResource]*accessReviewView
+
+	// lock guards the results of the checks.
+	lock sync.Mutex
+}
+
+// accessReviewView holds the namespaces in which a resource may be listed and
+// watched.
+type accessReviewView struct {
+	*namespaceSet
+
+	reviewer *accessReviewer
+	gvr      schema.GroupVersionResource
+
+	// allowed holds the result of the last check of each candidate, and
+	// version counts the namespaces computed from them.  They are guarded
+	// by the reviewer's lock.
+	allowed map[string]bool
+	version uint64
+}
+
+var _ AccessReviewNamespaceSet = &accessReviewView{}
+
+// NewAccessReviewNamespaceSet returns a new AccessReviewNamespaceSet holding
+// the namespaces of the given candidate set in which the client is allowed to
+// list and watch the given resource.  Feeding it to an informer instead of the
+// candidates avoids endless errors from namespaces that can't be watched.
+// The results are cached and checked again as the given config specifies.
+func NewAccessReviewNamespaceSet(client kubernetes.Interface, candidates NamespaceSet,
+	gvr schema.GroupVersionResource, config AccessReviewConfig,
+) AccessReviewNamespaceSet {
+	r := &accessReviewer{
+		client:     client,
+		candidates: candidates,
+		config:     config,
+		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		views:      make(map[schema.GroupVersionResource]*accessReviewView),
+	}
+
+	view := r.view(gvr)
+
+	candidates.AddHandler(NamespaceSetHandlerFuncs{
+		AddFunc:    r.enqueue,
+		RemoveFunc: r.enqueue,
+	})
+
+	if config.RoleBindings != nil {
+		changed := func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			if o, err := meta.Accessor(obj); err == nil && r.candidates.Contains(o.GetNamespace()) {
+				r.enqueue(o.GetNamespace())
+			}
+		}
+
+		_, err := config.RoleBindings.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    changed,
+			UpdateFunc: func(_, newObj interface{}) { changed(newObj) },
+			DeleteFunc: changed,
+		})
+		if err != nil {
+			klog.Errorf("Failed to watch role bindings: %v", err)
+		}
+	}
+
+	return view
+}
+
+// view returns the view for the given resource, creating it and queueing
+// checks for every candidate if it doesn't exist yet.
+func (r *accessReviewer) view(gvr schema.GroupVersionResource) *accessReviewView {
+	r.viewsLock.Lock()
+
+	if v, ok := r.views[gvr]; ok {
+		r.viewsLock.Unlock()
+		return v
+	}
+
+	v := &accessReviewView{
+		namespaceSet: &namespaceSet{},
+		reviewer:     r,
+		gvr:          gvr,
+		allowed:      make(map[string]bool),
+	}
+
+	r.views[gvr] = v
+	r.viewsLock.Unlock()
+
+	// Candidates added from here on are queued by the handler as well.
+	for _, namespace := range r.candidates.List() {
+		r.queue.Add(accessReviewKey{gvr: gvr, namespace: namespace})
+	}
+
+	r.lock.Lock()
+	namespaces, version := v.sync()
+	r.lock.Unlock()
+
+	v.setNamespacesAt(namespaces, version)
+
+	return v
+}
+
+// enqueue queues checks of the given namespace for every view.
+func (r *accessReviewer) enqueue(namespace string) {
+	r.viewsLock.RLock()
+	defer r.viewsLock.RUnlock()
+
+	for gvr := range r.views {
+		r.queue.Add(accessReviewKey{gvr: gvr, namespace: namespace})
+	}
+}
+
+// enqueueAll queues checks of every candidate for every view.
+func (r *accessReviewer) enqueueAll() {
+	for _, namespace := range r.candidates.List() {
+		r.enqueue(namespace)
+	}
+}
+
+// run runs the checks until the stop channel is closed.
+func (r *accessReviewer) run(stopCh <-chan struct{}) {
+	defer r.queue.ShutDown()
+
+	for n := 0; n < accessReviewWorkers; n++ {
+		go wait.Until(r.work, time.Second, stopCh)
+	}
+
+	if r.config.RecheckPeriod > 0 {
+		go wait.Until(r.enqueueAll, r.config.RecheckPeriod, stopCh)
+	}
+
+	// Views of an empty candidate set have nothing to check, so they are
+	// initialized once the candidates are.
+	ctx := wait.ContextForChannel(stopCh)
+	err := wait.PollUntilContextCancel(ctx, 100*time.Millisecond, true, func(ctx context.Context) (bool, error) {
+		return r.candidates.Initialized(), nil
+	})
+	if err == nil {
+		r.syncAll()
+	}
+
+	<-stopCh
+}
+
+// syncAll updates the namespaces of every view.
+func (r *accessReviewer) syncAll() {
+	r.viewsLock.RLock()
+	views := make([]*accessReviewView, 0, len(r.views))
+	for _, v := range r.views {
+		views = append(views, v)
+	}
+	r.viewsLock.RUnlock()
+
+	namespaces := make([][]string, len(views))
+	versions := make([]uint64, len(views))
+
+	r.lock.Lock()
+	for idx, v := range views {
+		namespaces[idx], versions[idx] = v.sync()
+	}
+	r.lock.Unlock()
+
+	for idx, v := range views {
+		v.setNamespacesAt(namespaces[idx], versions[idx])
+	}
+}
+
+// work processes queued checks until the queue is shut down.
+func (r *accessReviewer) work() {
+	for {
+		item, shutdown := r.queue.Get()
+		if shutdown {
+			return
+		}
+
+		key := item.(accessReviewKey)
+
+		if err := r.check(key); err != nil {
+			klog.Errorf("Failed to review access to %v in namespace %q: %v", key.gvr, key.namespace, err)
+			r.queue.AddRateLimited(key)
+		} else {
+			r.queue.Forget(key)
+		}
+
+		r.queue.Done(key)
+	}
+}
+
+// check reviews access for the given key and updates its view.  Namespaces
+// that are no longer candidates are dropped from the view.
+func (r *accessReviewer) check(key accessReviewKey) error {
+	r.viewsLock.RLock()
+	v := r.views[key.gvr]
+	r.viewsLock.RUnlock()
+
+	if !r.candidates.Contains(key.namespace) {
+		v.update(key.namespace, nil)
+		return nil
+	}
+
+	allowed := true
+	for _, verb := range []string{"list", "watch"} {
+		ok, err := r.review(key, verb)
+		if err != nil {
+			return err
+		}
+
+		allowed = allowed && ok
+	}
+
+	v.update(key.namespace, &allowed)
+
+	return nil
+}
+
+// review returns whether the client may use the given verb on the key's
+// resource in its namespace.
+func (r *accessReviewer) review(key accessReviewKey, verb string) (bool, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: key.namespace,
+				Verb:      verb,
+				Group:     key.gvr.Group,
+				Version:   key.gvr.Version,
+				Resource:  key.gvr.Resource,
+			},
+		},
+	}
+
+	res, err := r.client.AuthorizationV1().SelfSubjectAccessReviews().Create(context.TODO(), review, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	return res.Status.Allowed, nil
+}
+
+// update records the result of a check of the given namespace, or forgets it
+// if allowed is nil, and updates the view's namespaces.
+func (v *accessReviewView) update(namespace string, allowed *bool) {
+	v.reviewer.lock.Lock()
+
+	if allowed == nil {
+		delete(v.allowed, namespace)
+	} else {
+		v.allowed[namespace] = *allowed
+	}
+
+	namespaces, version := v.sync()
+	v.reviewer.lock.Unlock()
+
+	v.setNamespacesAt(namespaces, version)
+}
+
+// sync returns the allowed candidates, and the version to set them at as the
+// view's namespaces, which is zero if they can't be set yet.  The view is
+// initialized once every candidate has been checked.  The reviewer's lock
+// must be held.
+func (v *accessReviewView) sync() ([]string, uint64) {
+	candidates := v.reviewer.candidates.List()
+
+	if !v.Initialized() {
+		if !v.reviewer.candidates.Initialized() {
+			return nil, 0
+		}
+
+		for _, c := range candidates {
+			if _, ok := v.allowed[c]; !ok {
+				return nil, 0
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		if v.allowed[c] {
+			namespaces = append(namespaces, c)
+		}
+	}
+
+	v.version++
+
+	return namespaces, v.version
+}
+
+// SetNamespaces is ignored, since the view's namespaces are those that passed
+// their access reviews.
+func (v *accessReviewView) SetNamespaces(namespaces []string) {
+	klog.Errorf("Ignoring SetNamespaces(%q) on an access review namespace set", namespaces)
+}
+
+// Run checks the namespaces for every view until the stop channel is closed.
+func (v *accessReviewView) Run(stopCh <-chan struct{}) {
+	v.reviewer.run(stopCh)
+}
+
+// ForResource returns the view for the given resource.
+func (v *accessReviewView) ForResource(gvr schema.GroupVersionResource) NamespaceSet {
+	return v.reviewer.view(gvr)
+}
@@ -0,0 +1,181 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	podsGVR    = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	secretsGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// accessRules answers SelfSubjectAccessReviews from a set of allowed
+// resource, verb, and namespace triples.
+type accessRules struct {
+	lock    sync.Mutex
+	allowed map[string]bool
+}
+
+func (a *accessRules) allow(resource, verb, namespace string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.allowed[resource+"/"+verb+"/"+namespace] = true
+}
+
+func (a *accessRules) react(action k8stesting.Action) (bool, runtime.Object, error) {
+	review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+	attrs := review.Spec.ResourceAttributes
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	review.Status.Allowed = a.allowed[attrs.Resource+"/"+attrs.Verb+"/"+attrs.Namespace]
+
+	return true, review, nil
+}
+
+func newAccessClient() (*fake.Clientset, *accessRules) {
+	client := fake.NewSimpleClientset()
+	rules := &accessRules{allowed: make(map[string]bool)}
+	client.PrependReactor("create", "selfsubjectaccessreviews", rules.react)
+
+	return client, rules
+}
+
+func TestAccessReviewNamespaceSet(t *testing.T) {
+	client, rules := newAccessClient()
+
+	for _, verb := range []string{"list", "watch"} {
+		rules.allow("pods", verb, "ns1")
+		rules.allow("secrets", verb, "ns3")
+	}
+	rules.allow("pods", "list", "ns2")
+
+	factory := informers.NewSharedInformerFactory(client, 0)
+	roleBindings := factory.Rbac().V1().RoleBindings().Informer()
+
+	candidates := xnsinformers.NewNamespaceSet("ns1", "ns2", "ns3")
+	namespaces := xnsinformers.NewAccessReviewNamespaceSet(client, candidates, podsGVR,
+		xnsinformers.AccessReviewConfig{RoleBindings: roleBindings})
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	secrets := namespaces.ForResource(secretsGVR)
+	secretsRecorder := &namespaceSetRecorder{}
+	secrets.AddHandler(secretsRecorder)
+
+	if namespaces.Initialized() {
+		t.Fatalf("Expected namespace set not to be initialized before it runs")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	factory.Start(stop)
+	factory.WaitForCacheSync(stop)
+	go namespaces.Run(stop)
+
+	waitForInitialized(t, namespaces)
+	waitForInitialized(t, secrets)
+
+	// Namespaces where only list is allowed are left out.
+	recorder.waitFor(t, "add ns1")
+	secretsRecorder.waitFor(t, "add ns3")
+
+	recorder.reset()
+	secretsRecorder.reset()
+
+	// Granting watch in ns2 takes effect once a RoleBinding there changes.
+	rules.allow("pods", "watch", "ns2")
+
+	_, err := client.RbacV1().RoleBindings("ns2").Create(context.TODO(),
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "ns2", Name: "watch-pods"}}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create role binding: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns2")
+	recorder.reset()
+
+	// Namespaces removed from the candidates are removed from every view.
+	candidates.SetNamespaces([]string{"ns1"})
+
+	recorder.waitFor(t, "remove ns2")
+	secretsRecorder.waitFor(t, "remove ns3")
+}
+
+func TestAccessReviewNamespaceSetEmpty(t *testing.T) {
+	client, _ := newAccessClient()
+
+	candidates := xnsinformers.NewNamespaceSet()
+	candidates.SetNamespaces(make([]string, 0))
+
+	namespaces := xnsinformers.NewAccessReviewNamespaceSet(client, candidates, podsGVR, xnsinformers.AccessReviewConfig{})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	if list := namespaces.List(); len(list) != 0 {
+		t.Errorf("Expected no namespaces, got: %v", list)
+	}
+}
+
+func TestAccessReviewNamespaceSetReentrantHandler(t *testing.T) {
+	client, rules := newAccessClient()
+
+	for _, verb := range []string{"list", "watch"} {
+		rules.allow("pods", verb, "ns1")
+		rules.allow("secrets", verb, "ns1")
+	}
+
+	candidates := xnsinformers.NewNamespaceSet("ns1")
+	namespaces := xnsinformers.NewAccessReviewNamespaceSet(client, candidates, podsGVR, xnsinformers.AccessReviewConfig{})
+
+	// A handler asking for another view calls back into the set while its
+	// events are being sent.
+	views := make(chan xnsinformers.NamespaceSet, 1)
+	namespaces.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			select {
+			case views <- namespaces.ForResource(secretsGVR):
+			default:
+			}
+		},
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+
+	var secrets xnsinformers.NamespaceSet
+	select {
+	case secrets = <-views:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("Timed out waiting for the handler")
+	}
+
+	waitForInitialized(t, secrets)
+
+	if list := secrets.List(); !reflect.DeepEqual(list, []string{"ns1"}) {
+		t.Errorf("Expected namespaces [ns1], got: %v", list)
+	}
+}
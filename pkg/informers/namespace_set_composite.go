@@ -0,0 +1,207 @@
+package informers
+
+import (
+	"sync"
+
+	"github.com/maistra/xns-informer/pkg/internal/sets"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// combineFunc combines the namespaces of the inputs of a composite set.
+type combineFunc func(inputs []sets.Set) sets.Set
+
+// compositeNamespaceSet is a NamespaceSet combining the namespaces of other
+// sets.  It follows their namespaces from their events instead of listing
+// them, so the changes of each input are combined in the order they were made.
+// Inputs built on namespaceSet report the end of each change, so only its net
+// result is combined; other inputs are combined after every event.  Since
+// other inputs send no events when initialized without namespaces, whether
+// they are initialized is checked again whenever an input reports a change.
+type compositeNamespaceSet struct {
+	*namespaceSet
+
+	inputs  []NamespaceSet
+	combine combineFunc
+
+	lock        sync.Mutex
+	members     []sets.Set
+	initialized []bool
+	version     uint64
+}
+
+var _ NamespaceSet = &compositeNamespaceSet{}
+
+// compositeInputHandler follows the namespaces of one input of a composite set.
+type compositeInputHandler struct {
+	set   *compositeNamespaceSet
+	index int
+
+	// batched is set once the input reports the end of a change.  It is
+	// guarded by the set's lock.
+	batched bool
+}
+
+var _ namespaceSetBatchHandler = &compositeInputHandler{}
+
+func (h *compositeInputHandler) OnAdd(namespace string) {
+	h.update(func(s sets.Set) { s.Insert(namespace) })
+}
+
+func (h *compositeInputHandler) OnRemove(namespace string) {
+	h.update(func(s sets.Set) { s.Delete(namespace) })
+}
+
+// update applies a change to the input's namespaces.  An input sending events
+// has been initialized.
+func (h *compositeInputHandler) update(change func(sets.Set)) {
+	h.set.lock.Lock()
+
+	change(h.set.members[h.index])
+	h.set.initialized[h.index] = true
+
+	var (
+		namespaces []string
+		version    uint64
+	)
+	if !h.batched {
+		namespaces, version = h.set.combined()
+	}
+
+	h.set.lock.Unlock()
+
+	h.set.setNamespacesAt(namespaces, version)
+}
+
+func (h *compositeInputHandler) onBatch(initialized bool) {
+	h.set.lock.Lock()
+	h.batched = true
+	h.set.initialized[h.index] = initialized
+	h.set.lock.Unlock()
+
+	h.set.refresh()
+}
+
+func newCompositeNamespaceSet(inputs []NamespaceSet, combine combineFunc) *compositeNamespaceSet {
+	s := &compositeNamespaceSet{
+		namespaceSet: &namespaceSet{},
+		inputs:       inputs,
+		combine:      combine,
+		members:      make([]sets.Set, len(inputs)),
+		initialized:  make([]bool, len(inputs)),
+	}
+
+	for idx, input := range inputs {
+		s.members[idx] = sets.NewSet()
+		s.initialized[idx] = input.Initialized()
+	}
+
+	for idx, input := range inputs {
+		input.AddHandler(&compositeInputHandler{set: s, index: idx})
+	}
+
+	s.refresh()
+
+	return s
+}
+
+// combined returns the combination of the inputs, once every input has been
+// initialized, and the version to set it at, which is zero until then.  The
+// lock must be held.
+func (s *compositeNamespaceSet) combined() ([]string, uint64) {
+	for _, initialized := range s.initialized {
+		if !initialized {
+			return nil, 0
+		}
+	}
+
+	s.version++
+
+	return s.combine(s.members).UnsortedList(), s.version
+}
+
+// SetNamespaces is ignored, since the set's namespaces are combined from its
+// inputs.
+func (s *compositeNamespaceSet) SetNamespaces(namespaces []string) {
+	klog.Errorf("Ignoring SetNamespaces(%q) on a composite namespace set", namespaces)
+}
+
+// refresh checks whether the inputs are initialized, since inputs not built on
+// namespaceSet send no events when initialized without namespaces, and sets
+// the combined namespaces.  The inputs are checked without the lock held, so
+// their handlers aren't held up.
+func (s *compositeNamespaceSet) refresh() {
+	initialized := make([]bool, len(s.inputs))
+	for idx, input := range s.inputs {
+		initialized[idx] = input.Initialized()
+	}
+
+	s.lock.Lock()
+	for idx := range initialized {
+		s.initialized[idx] = s.initialized[idx] || initialized[idx]
+	}
+
+	namespaces, version := s.combined()
+	s.lock.Unlock()
+
+	s.setNamespacesAt(namespaces, version)
+}
+
+// Union returns a NamespaceSet holding the namespaces in any of the given
+// sets.  It is initialized once every one of them is, and its handlers are
+// only sent the net changes, so a namespace moving from one input to another
+// sends none.  Calls to its SetNamespaces are ignored.
+func Union(a NamespaceSet, b ...NamespaceSet) NamespaceSet {
+	return newCompositeNamespaceSet(append([]NamespaceSet{a}, b...), func(inputs []sets.Set) sets.Set {
+		res := sets.NewSet()
+		for _, s := range inputs {
+			res = res.Union(s)
+		}
+
+		return res
+	})
+}
+
+// Intersect returns a NamespaceSet holding the namespaces in every one of the
+// given sets.  A set holding metav1.NamespaceAll matches every namespace.  It
+// behaves like Union otherwise.
+func Intersect(a NamespaceSet, b ...NamespaceSet) NamespaceSet {
+	return newCompositeNamespaceSet(append([]NamespaceSet{a}, b...), func(inputs []sets.Set) sets.Set {
+		// A nil result matches every namespace.
+		var res sets.Set
+		for _, s := range inputs {
+			switch {
+			case s.Contains(metav1.NamespaceAll):
+				continue
+			case res == nil:
+				res = s.Union(nil)
+			default:
+				res = res.Intersection(s)
+			}
+		}
+
+		if res == nil {
+			return sets.NewSet(metav1.NamespaceAll)
+		}
+
+		return res
+	})
+}
+
+// Difference returns a NamespaceSet holding the namespaces in a that aren't in
+// b.  If b holds metav1.NamespaceAll, the difference is empty.  If only a holds
+// it, the difference holds it as well, since a NamespaceSet can't hold every
+// namespace but some.  It behaves like Union otherwise.
+func Difference(a, b NamespaceSet) NamespaceSet {
+	return newCompositeNamespaceSet([]NamespaceSet{a, b}, func(inputs []sets.Set) sets.Set {
+		if inputs[1].Contains(metav1.NamespaceAll) {
+			return sets.NewSet()
+		}
+
+		if inputs[0].Contains(metav1.NamespaceAll) {
+			return sets.NewSet(metav1.NamespaceAll)
+		}
+
+		return inputs[0].Difference(inputs[1])
+	})
+}
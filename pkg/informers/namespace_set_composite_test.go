@@ -0,0 +1,182 @@
+package informers_test
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestUnion(t *testing.T) {
+	a := xnsinformers.NewNamespaceSet("ns1", "ns2")
+	b := xnsinformers.NewUninitializedNamespaceSet()
+
+	union := xnsinformers.Union(a, b)
+	recorder := &namespaceSetRecorder{}
+	union.AddHandler(recorder)
+
+	if union.Initialized() {
+		t.Fatalf("Expected union not to be initialized before all inputs are")
+	}
+
+	b.SetNamespaces([]string{"ns2", "ns3"})
+
+	if !union.Initialized() {
+		t.Fatalf("Expected union to be initialized")
+	}
+
+	recorder.waitFor(t, "add ns1", "add ns2", "add ns3")
+	recorder.reset()
+
+	// Moving ns2 from one input to the other sends no events.
+	a.SetNamespaces([]string{"ns1"})
+	b.SetNamespaces([]string{"ns2", "ns3", "ns4"})
+	b.SetNamespaces([]string{"ns2", "ns4"})
+
+	recorder.waitFor(t, "add ns4", "remove ns3")
+
+	if list := union.List(); !reflect.DeepEqual(list, []string{"ns1", "ns2", "ns4"}) {
+		t.Errorf("Expected namespaces [ns1 ns2 ns4], got: %v", list)
+	}
+}
+
+func TestUnionReentrantHandler(t *testing.T) {
+	a := xnsinformers.NewNamespaceSet("ns1")
+	b := xnsinformers.NewUninitializedNamespaceSet()
+
+	union := xnsinformers.Union(a, b)
+
+	// A handler checking the union calls back into it while its events are
+	// being sent.
+	var lists [][]string
+	union.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			_ = union.Initialized()
+			lists = append(lists, union.List())
+		},
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.SetNamespaces([]string{"ns2"})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("Timed out setting namespaces")
+	}
+
+	if !union.Initialized() {
+		t.Fatalf("Expected union to be initialized")
+	}
+
+	expected := [][]string{{"ns1", "ns2"}, {"ns1", "ns2"}}
+	if !reflect.DeepEqual(lists, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", lists, expected)
+	}
+}
+
+func TestUnionEmptyInputs(t *testing.T) {
+	a := xnsinformers.NewUninitializedNamespaceSet()
+	union := xnsinformers.Union(a, xnsinformers.NewNamespaceSet("ns1"))
+
+	if union.Initialized() {
+		t.Fatalf("Expected union not to be initialized before all inputs are")
+	}
+
+	// An input initialized without namespaces sends no events.
+	a.SetNamespaces(make([]string, 0))
+
+	if !union.Initialized() {
+		t.Errorf("Expected union to be initialized")
+	}
+}
+
+// staticNamespaceSet is a NamespaceSet not built on this package's sets, which
+// sends no events when initialized without namespaces.
+type staticNamespaceSet struct {
+	initialized atomic.Bool
+}
+
+func (s *staticNamespaceSet) Initialized() bool                           { return s.initialized.Load() }
+func (s *staticNamespaceSet) SetNamespaces([]string)                      { s.initialized.Store(true) }
+func (s *staticNamespaceSet) AddHandler(xnsinformers.NamespaceSetHandler) {}
+func (s *staticNamespaceSet) Contains(string) bool                        { return false }
+func (s *staticNamespaceSet) List() []string                              { return nil }
+
+func TestUnionForeignEmptyInput(t *testing.T) {
+	a := &staticNamespaceSet{}
+	b := xnsinformers.NewNamespaceSet("ns1")
+	union := xnsinformers.Union(a, b)
+
+	recorder := &namespaceSetRecorder{}
+	union.AddHandler(recorder)
+
+	a.SetNamespaces(nil)
+
+	// Initialized only reports the union's state, and the empty input is
+	// noticed once another input changes.
+	if union.Initialized() {
+		t.Fatalf("Expected union not to be initialized before another input changes")
+	}
+
+	b.SetNamespaces([]string{"ns1", "ns2"})
+
+	if !union.Initialized() {
+		t.Errorf("Expected union to be initialized")
+	}
+
+	recorder.waitFor(t, "add ns1", "add ns2")
+}
+
+func TestIntersect(t *testing.T) {
+	a := xnsinformers.NewNamespaceSet("ns1", "ns2", "ns3")
+	b := xnsinformers.NewNamespaceSet("ns2", "ns3", "ns4")
+	c := xnsinformers.NewNamespaceSet("")
+
+	intersection := xnsinformers.Intersect(a, b, c)
+	recorder := &namespaceSetRecorder{}
+	intersection.AddHandler(recorder)
+
+	recorder.waitFor(t, "add ns2", "add ns3")
+	recorder.reset()
+
+	b.SetNamespaces([]string{"ns1", "ns3"})
+	recorder.waitFor(t, "add ns1", "remove ns2")
+	recorder.reset()
+
+	c.SetNamespaces([]string{"ns3"})
+	recorder.waitFor(t, "remove ns1")
+
+	if list := intersection.List(); !reflect.DeepEqual(list, []string{"ns3"}) {
+		t.Errorf("Expected namespaces [ns3], got: %v", list)
+	}
+}
+
+func TestDifference(t *testing.T) {
+	a := xnsinformers.NewNamespaceSet("istio-system", "ns1", "ns2")
+	b := xnsinformers.NewNamespaceSet("ns2")
+
+	difference := xnsinformers.Difference(a, b)
+	recorder := &namespaceSetRecorder{}
+	difference.AddHandler(recorder)
+
+	recorder.waitFor(t, "add istio-system", "add ns1")
+	recorder.reset()
+
+	b.SetNamespaces([]string{"ns1"})
+	recorder.waitFor(t, "add ns2", "remove ns1")
+	recorder.reset()
+
+	a.SetNamespaces([]string{"istio-system", "ns1", "ns2", "ns3"})
+	recorder.waitFor(t, "add ns3")
+	recorder.reset()
+
+	b.SetNamespaces([]string{""})
+	recorder.waitFor(t, "remove istio-system", "remove ns2", "remove ns3")
+}
@@ -0,0 +1,94 @@
+package informers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DiscoverySelectorNamespaceSet is a NamespaceSet holding the namespaces
+// matched by a list of label selectors, like Istio's MeshConfig
+// discoverySelectors.
+type DiscoverySelectorNamespaceSet interface {
+	WatchedNamespaceSet
+
+	// SetDiscoverySelectors replaces the selectors and updates the set to
+	// match.  Informers using the set only add and remove the namespaces that
+	// changed.
+	SetDiscoverySelectors(selectors []*metav1.LabelSelector) error
+}
+
+type discoverySelectorNamespaceSet struct {
+	*watchedNamespaceSet
+}
+
+// NewDiscoverySelectorNamespaceSet returns a new NamespaceSet holding the
+// namespaces matched by the given selectors, with the same semantics as
+// Istio's discoverySelectors: a namespace is included if it matches any of the
+// selectors, and all namespaces are included if there are none.  It watches
+// all Namespace objects through the given client once it is run.
+func NewDiscoverySelectorNamespaceSet(client kubernetes.Interface, selectors []*metav1.LabelSelector,
+) (DiscoverySelectorNamespaceSet, error) {
+	return newDiscoverySelectorNamespaceSet(newNamespaceInformer(client, nil), true, selectors)
+}
+
+// NewDiscoverySelectorNamespaceSetForInformer is like
+// NewDiscoverySelectorNamespaceSet, but uses the given Namespace informer,
+// which must be run by the caller and must not filter namespaces.
+func NewDiscoverySelectorNamespaceSetForInformer(informer cache.SharedIndexInformer, selectors []*metav1.LabelSelector,
+) (DiscoverySelectorNamespaceSet, error) {
+	return newDiscoverySelectorNamespaceSet(informer, false, selectors)
+}
+
+func newDiscoverySelectorNamespaceSet(informer cache.SharedIndexInformer, ownsInformer bool,
+	selectors []*metav1.LabelSelector,
+) (DiscoverySelectorNamespaceSet, error) {
+	match, err := matchDiscoverySelectors(selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	return &discoverySelectorNamespaceSet{newWatchedNamespaceSet(informer, ownsInformer, matchNamespaces(match))}, nil
+}
+
+// SetDiscoverySelectors replaces the selectors.  The set is left alone if any
+// of them are invalid.
+func (s *discoverySelectorNamespaceSet) SetDiscoverySelectors(selectors []*metav1.LabelSelector) error {
+	match, err := matchDiscoverySelectors(selectors)
+	if err != nil {
+		return err
+	}
+
+	s.setResolver(matchNamespaces(match))
+
+	return nil
+}
+
+// matchDiscoverySelectors returns a function matching namespaces that match
+// any of the given selectors, or all namespaces if there are none.
+func matchDiscoverySelectors(selectors []*metav1.LabelSelector) (namespaceMatchFunc, error) {
+	if len(selectors) == 0 {
+		return func(*v1.Namespace) bool { return true }, nil
+	}
+
+	parsed := make([]labels.Selector, 0, len(selectors))
+	for _, s := range selectors {
+		selector, err := metav1.LabelSelectorAsSelector(s)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, selector)
+	}
+
+	return func(ns *v1.Namespace) bool {
+		for _, selector := range parsed {
+			if selector.Matches(labels.Set(ns.Labels)) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,125 @@
+package informers_test
+
+import (
+	"reflect"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiscoverySelectorNamespaceSet(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("bookinfo", map[string]string{"istio-discovery": "enabled"}),
+		newNamespace("team-a", map[string]string{"team": "a", "env": "prod"}),
+		newNamespace("team-b", map[string]string{"team": "b", "env": "dev"}),
+		newNamespace("kube-system", nil),
+	)
+
+	selectors := []*metav1.LabelSelector{
+		{MatchLabels: map[string]string{"istio-discovery": "enabled"}},
+		{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "team", Operator: metav1.LabelSelectorOpExists},
+			{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod"}},
+		}},
+	}
+
+	namespaces, err := xnsinformers.NewDiscoverySelectorNamespaceSet(client, selectors)
+	if err != nil {
+		t.Fatalf("Failed to create namespace set: %v", err)
+	}
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	recorder.waitFor(t, "add bookinfo", "add team-a")
+	recorder.reset()
+
+	// Changing the selectors only fires events for the namespaces that
+	// changed.
+	err = namespaces.SetDiscoverySelectors([]*metav1.LabelSelector{
+		{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "team", Operator: metav1.LabelSelectorOpExists},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set discovery selectors: %v", err)
+	}
+
+	recorder.waitFor(t, "remove bookinfo", "add team-b")
+	recorder.reset()
+
+	// No selectors means all namespaces.
+	if err := namespaces.SetDiscoverySelectors(nil); err != nil {
+		t.Fatalf("Failed to set discovery selectors: %v", err)
+	}
+
+	recorder.waitFor(t, "add bookinfo", "add kube-system")
+
+	// Invalid selectors are rejected and leave the set alone.
+	err = namespaces.SetDiscoverySelectors([]*metav1.LabelSelector{
+		{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "Bogus"}}},
+	})
+	if err == nil {
+		t.Errorf("Expected an error for an invalid selector")
+	}
+
+	expected := []string{"bookinfo", "kube-system", "team-a", "team-b"}
+	if list := namespaces.List(); !reflect.DeepEqual(list, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", list, expected)
+	}
+}
+
+func TestDiscoverySelectorNamespaceSetReentrantHandler(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("bookinfo", map[string]string{"istio-discovery": "enabled"}),
+		newNamespace("team-a", map[string]string{"team": "a"}),
+	)
+
+	namespaces, err := xnsinformers.NewDiscoverySelectorNamespaceSet(client, []*metav1.LabelSelector{
+		{MatchLabels: map[string]string{"istio-discovery": "enabled"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create namespace set: %v", err)
+	}
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	// A handler changing the selectors calls back into the set while its
+	// events are being sent.
+	namespaces.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			if ns != "bookinfo" {
+				return
+			}
+
+			err := namespaces.SetDiscoverySelectors([]*metav1.LabelSelector{
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpExists},
+				}},
+			})
+			if err != nil {
+				t.Errorf("Failed to set discovery selectors: %v", err)
+			}
+		},
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+
+	recorder.waitFor(t, "add bookinfo", "remove bookinfo", "add team-a")
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{"team-a"}) {
+		t.Errorf("Expected namespaces [team-a], got: %v", list)
+	}
+}
@@ -0,0 +1,145 @@
+package informers
+
+import (
+	"path"
+	"sort"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// ExclusionNamespaceSet is a NamespaceSet holding every namespace except the
+// excluded ones.  It only holds metav1.NamespaceAll, so informers use a single
+// cluster-wide watch.  Informers created with a cache.ListerWatcher drop the
+// objects of excluded namespaces before they reach their caches and handlers,
+// and relist when the exclusions change, which handlers see as deletions for
+// newly excluded namespaces and additions for newly included ones.
+// SetNamespaces is ignored; use SetExcluded instead.
+type ExclusionNamespaceSet interface {
+	NamespaceSet
+
+	// Excluded returns the excluded namespaces and patterns.
+	Excluded() []string
+
+	// SetExcluded replaces the excluded namespaces.  Entries may be patterns
+	// as understood by path.Match, such as "openshift-*".
+	SetExcluded(excluded []string) error
+
+	// IsExcluded reports whether the given namespace is excluded.
+	IsExcluded(namespace string) bool
+
+	// AddExclusionHandler adds a function called after each change to the
+	// excluded namespaces.
+	AddExclusionHandler(handler func())
+}
+
+type exclusionNamespaceSet struct {
+	*namespaceSet
+
+	lock     sync.RWMutex
+	excluded []string
+	handlers []func()
+}
+
+var _ ExclusionNamespaceSet = &exclusionNamespaceSet{}
+
+// NewExclusionNamespaceSet returns a new ExclusionNamespaceSet holding every
+// namespace except the given ones, which may be patterns as understood by
+// path.Match.  An error is returned if any pattern is malformed.
+func NewExclusionNamespaceSet(excluded ...string) (ExclusionNamespaceSet, error) {
+	if err := validatePatterns(excluded); err != nil {
+		return nil, err
+	}
+
+	s := &exclusionNamespaceSet{
+		namespaceSet: &namespaceSet{},
+		excluded:     append([]string(nil), excluded...),
+	}
+
+	s.namespaceSet.SetNamespaces([]string{metav1.NamespaceAll})
+
+	return s, nil
+}
+
+// validatePatterns returns an error if any of the given patterns is malformed.
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Excluded returns the sorted excluded namespaces and patterns.
+func (s *exclusionNamespaceSet) Excluded() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	res := append([]string(nil), s.excluded...)
+	sort.Strings(res)
+
+	return res
+}
+
+// SetExcluded replaces the excluded namespaces and calls the exclusion
+// handlers.  An error is returned if any pattern is malformed.
+func (s *exclusionNamespaceSet) SetExcluded(excluded []string) error {
+	if err := validatePatterns(excluded); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	s.excluded = append([]string(nil), excluded...)
+	handlers := append([]func(){}, s.handlers...)
+	s.lock.Unlock()
+
+	// Handlers are called without the lock held, so they may look up
+	// exclusions.
+	for _, h := range handlers {
+		h()
+	}
+
+	return nil
+}
+
+// IsExcluded reports whether the given namespace matches any of the excluded
+// namespaces and patterns.  metav1.NamespaceAll is never excluded.
+func (s *exclusionNamespaceSet) IsExcluded(namespace string) bool {
+	if namespace == metav1.NamespaceAll {
+		return false
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, pattern := range s.excluded {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetNamespaces is ignored, since the set always holds metav1.NamespaceAll.
+// Use SetExcluded to change the namespaces it holds.
+func (s *exclusionNamespaceSet) SetNamespaces(namespaces []string) {
+	klog.Errorf("Ignoring SetNamespaces(%q) on an exclusion namespace set", namespaces)
+}
+
+// Contains reports whether the given namespace isn't excluded.
+func (s *exclusionNamespaceSet) Contains(namespace string) bool {
+	return !s.IsExcluded(namespace)
+}
+
+// AddExclusionHandler adds a function called after each change to the
+// excluded namespaces.
+func (s *exclusionNamespaceSet) AddExclusionHandler(handler func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.handlers = append(s.handlers, handler)
+}
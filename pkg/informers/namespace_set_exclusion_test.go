@@ -0,0 +1,141 @@
+package informers_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestExclusionNamespaceSet(t *testing.T) {
+	namespaces, err := xnsinformers.NewExclusionNamespaceSet("kube-system", "openshift-*")
+	if err != nil {
+		t.Fatalf("Failed to create namespace set: %v", err)
+	}
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{""}) {
+		t.Errorf("Expected only NamespaceAll, got: %v", list)
+	}
+
+	for ns, excluded := range map[string]bool{
+		"kube-system":   true,
+		"openshift-dns": true,
+		"openshift":     false,
+		"app":           false,
+		"":              false,
+	} {
+		if got := namespaces.IsExcluded(ns); got != excluded {
+			t.Errorf("Expected IsExcluded(%q) to be %v, got %v", ns, excluded, got)
+		}
+
+		if got := namespaces.Contains(ns); got == excluded {
+			t.Errorf("Expected Contains(%q) to be %v, got %v", ns, !excluded, got)
+		}
+	}
+
+	// Setting namespaces directly is ignored.
+	namespaces.SetNamespaces([]string{"kube-system"})
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{""}) {
+		t.Errorf("Expected SetNamespaces to be ignored, got: %v", list)
+	}
+
+	if namespaces.Contains("kube-system") || !namespaces.Contains("app") {
+		t.Errorf("Expected SetNamespaces not to change exclusions")
+	}
+
+	if err := namespaces.SetExcluded([]string{"["}); err == nil {
+		t.Errorf("Expected an error for a malformed pattern")
+	}
+
+	changed := 0
+	namespaces.AddExclusionHandler(func() { changed++ })
+
+	if err := namespaces.SetExcluded([]string{"b", "a"}); err != nil {
+		t.Fatalf("Failed to set exclusions: %v", err)
+	}
+
+	if changed != 1 {
+		t.Errorf("Expected the exclusion handler to be called once, got %d", changed)
+	}
+
+	if excluded := namespaces.Excluded(); !reflect.DeepEqual(excluded, []string{"a", "b"}) {
+		t.Errorf("Expected exclusions [a b], got: %v", excluded)
+	}
+}
+
+func TestMultiNamespaceInformerExclusions(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("kube-system", "sys1"))
+	source.Add(newPod("openshift-dns", "dns1"))
+	source.Add(newPod("app", "app1"))
+
+	namespaces, err := xnsinformers.NewExclusionNamespaceSet("kube-system", "openshift-*")
+	if err != nil {
+		t.Fatalf("Failed to create namespace set: %v", err)
+	}
+
+	informer := xnsinformers.NewMultiNamespaceInformerWithListerWatcher(namespaces, &v1.Pod{}, 0, cache.Indexers{},
+		func(namespace string) cache.ListerWatcher {
+			if namespace != "" {
+				t.Errorf("Unexpected informer for namespace %q", namespace)
+			}
+			return source
+		},
+	)
+
+	recorder := &eventRecorder{}
+	if _, err := informer.AddEventHandler(recorder); err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	recorder.waitFor(t, 1)
+	expectEvents(t, recorder.reset(), "add:app1:3")
+
+	// Objects in excluded namespaces are dropped from watches as well.
+	source.Add(newPod("openshift-dns", "dns2"))
+	source.Add(newPod("app", "app2"))
+
+	recorder.waitFor(t, 1)
+	expectEvents(t, recorder.reset(), "add:app2:5")
+
+	for _, key := range []string{"kube-system/sys1", "openshift-dns/dns1", "openshift-dns/dns2"} {
+		if _, exists, _ := informer.GetIndexer().GetByKey(key); exists {
+			t.Errorf("Expected %s to be excluded", key)
+		}
+	}
+
+	// Changing the exclusions relists, deleting newly excluded objects and
+	// adding newly included ones.
+	if err := namespaces.SetExcluded([]string{"app", "kube-system"}); err != nil {
+		t.Fatalf("Failed to set exclusions: %v", err)
+	}
+
+	recorder.waitFor(t, 4)
+	expectEvents(t, recorder.reset(), "add:dns1:2", "add:dns2:4", "delete:app1:3", "delete:app2:5")
+
+	waitForKeys(t, informer, "openshift-dns/dns1", "openshift-dns/dns2")
+	waitForNoKeys(t, informer, "app/app1", "app/app2")
+}
+
+// expectEvents compares the given events to the expected ones, in any order.
+func expectEvents(t *testing.T, events []string, expected ...string) {
+	t.Helper()
+
+	sort.Strings(events)
+	sort.Strings(expected)
+
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", events, expected)
+	}
+}
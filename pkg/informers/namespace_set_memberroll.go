@@ -0,0 +1,76 @@
+package informers
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// MemberRollGVR is the resource of Maistra's ServiceMeshMemberRoll.
+var MemberRollGVR = schema.GroupVersionResource{
+	Group:    "maistra.io",
+	Version:  "v1",
+	Resource: "servicemeshmemberrolls",
+}
+
+// NewMemberRollNamespaceSet returns a new NamespaceSet holding the namespaces
+// in the status.configuredMembers of the ServiceMeshMemberRoll with the given
+// name in the control plane namespace, plus the control plane namespace
+// itself.  While the member roll doesn't exist, the set only holds the control
+// plane namespace.  The member roll is watched through the given client once
+// the set is run.
+func NewMemberRollNamespaceSet(client dynamic.Interface, controlPlane, name string) WatchedNamespaceSet {
+	tweak := func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+
+	resource := client.Resource(MemberRollGVR).Namespace(controlPlane)
+
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				tweak(&options)
+				return resource.List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				tweak(&options)
+				return resource.Watch(context.TODO(), options)
+			},
+		},
+		&unstructured.Unstructured{},
+		0,
+		cache.Indexers{},
+	)
+
+	resolve := func(store cache.Store) []string {
+		namespaces := []string{controlPlane}
+
+		obj, exists, err := store.GetByKey(controlPlane + "/" + name)
+		if err != nil {
+			klog.Errorf("Failed to get member roll %s/%s: %v", controlPlane, name, err)
+		}
+
+		if u, ok := obj.(*unstructured.Unstructured); exists && ok {
+			members, _, err := unstructured.NestedStringSlice(u.Object, "status", "configuredMembers")
+			if err != nil {
+				klog.Errorf("Invalid configured members in member roll %s/%s: %v", controlPlane, name, err)
+			}
+
+			namespaces = append(namespaces, members...)
+		}
+
+		return namespaces
+	}
+
+	// The member roll is a single object, so every change resolves the set
+	// from it again.
+	return newWatchedNamespaceSet(informer, true, namespaceResolver{all: resolve})
+}
@@ -0,0 +1,108 @@
+package informers_test
+
+import (
+	"context"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newMemberRoll(namespace, name string, members ...string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("maistra.io/v1")
+	u.SetKind("ServiceMeshMemberRoll")
+	u.SetNamespace(namespace)
+	u.SetName(name)
+
+	configured := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		configured = append(configured, m)
+	}
+
+	_ = unstructured.SetNestedSlice(u.Object, configured, "status", "configuredMembers")
+
+	return u
+}
+
+func TestMemberRollNamespaceSet(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			xnsinformers.MemberRollGVR: "ServiceMeshMemberRollList",
+		},
+		newMemberRoll("istio-system", "default", "ns1", "ns2"),
+		newMemberRoll("istio-system", "other", "ns3"),
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	namespaces := xnsinformers.NewMemberRollNamespaceSet(client, "istio-system", "default")
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	if namespaces.Initialized() {
+		t.Fatalf("Namespace set initialized before it was run")
+	}
+
+	go namespaces.Run(stopCh)
+
+	waitForInitialized(t, namespaces)
+	recorder.waitFor(t, "add istio-system", "add ns1", "add ns2")
+
+	resource := client.Resource(xnsinformers.MemberRollGVR).Namespace("istio-system")
+
+	recorder.reset()
+	_, err := resource.UpdateStatus(context.TODO(), newMemberRoll("istio-system", "default", "ns2", "ns4"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update member roll: %v", err)
+	}
+
+	recorder.waitFor(t, "remove ns1", "add ns4")
+
+	recorder.reset()
+	if err := resource.Delete(context.TODO(), "default", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete member roll: %v", err)
+	}
+
+	recorder.waitFor(t, "remove ns2", "remove ns4")
+
+	if got := namespaces.List(); len(got) != 1 || got[0] != "istio-system" {
+		t.Errorf("Expected only the control plane namespace, got %v", got)
+	}
+}
+
+func TestMemberRollNamespaceSetMissingRoll(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			xnsinformers.MemberRollGVR: "ServiceMeshMemberRollList",
+		},
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	namespaces := xnsinformers.NewMemberRollNamespaceSet(client, "istio-system", "default")
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	go namespaces.Run(stopCh)
+
+	waitForInitialized(t, namespaces)
+	recorder.waitFor(t, "add istio-system")
+
+	recorder.reset()
+	_, err := client.Resource(xnsinformers.MemberRollGVR).Namespace("istio-system").
+		Create(context.TODO(), newMemberRoll("istio-system", "default", "ns1"), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create member roll: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns1")
+}
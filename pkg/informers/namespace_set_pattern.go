@@ -0,0 +1,248 @@
+package informers
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/maistra/xns-informer/pkg/internal/sets"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// PatternNamespaceSet is a WatchedNamespaceSet whose entries may be patterns
+// as well as namespaces.  Entries starting with "^" are regular expressions,
+// and entries holding any of "*?[" are patterns as understood by path.Match,
+// such as "tenant-*".  Patterns are matched against the live Namespace objects,
+// while namespaces are held whether or not they exist.  SetNamespaces replaces
+// the entries, skipping malformed patterns.
+//
+// List returns the concrete namespaces: the literal entries along with every
+// namespace matched by a pattern, never the patterns themselves, so the set
+// can be used wherever a NamespaceSet is.  A list of names can't tell which
+// pattern matched each namespace, so PatternMatches reports that instead.
+type PatternNamespaceSet interface {
+	WatchedNamespaceSet
+
+	// Entries returns the namespaces and patterns the set was given.
+	Entries() []string
+
+	// PatternMatches returns the concrete namespaces matched by each pattern,
+	// keyed by the pattern.  Their union with the literal entries is List.
+	PatternMatches() map[string][]string
+}
+
+// namespacePattern matches namespace names against a single pattern entry.
+type namespacePattern struct {
+	entry string
+	regex *regexp.Regexp
+}
+
+func (p *namespacePattern) match(namespace string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(namespace)
+	}
+
+	ok, _ := path.Match(p.entry, namespace)
+
+	return ok
+}
+
+// parseEntry returns the pattern for the given entry, or nil if the entry is
+// a namespace.  Namespace names can't hold any of the characters that mark an
+// entry as a pattern.
+func parseEntry(entry string) (*namespacePattern, error) {
+	switch {
+	case strings.HasPrefix(entry, "^"):
+		regex, err := regexp.Compile(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", entry, err)
+		}
+
+		return &namespacePattern{entry: entry, regex: regex}, nil
+	case strings.ContainsAny(entry, "*?["):
+		if _, err := path.Match(entry, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", entry, err)
+		}
+
+		return &namespacePattern{entry: entry}, nil
+	default:
+		return nil, nil
+	}
+}
+
+type patternNamespaceSet struct {
+	*watchedNamespaceSet
+
+	lock    sync.Mutex
+	entries []string
+	matches map[string]sets.Set
+}
+
+var _ PatternNamespaceSet = &patternNamespaceSet{}
+
+// NewPatternNamespaceSet returns a new PatternNamespaceSet holding the given
+// namespaces and the namespaces matched by the given patterns.  It watches
+// Namespace objects through the given client once it is run.  An error is
+// returned if any pattern is malformed.
+func NewPatternNamespaceSet(client kubernetes.Interface, entries ...string) (PatternNamespaceSet, error) {
+	return newPatternNamespaceSet(newNamespaceInformer(client, nil), true, entries)
+}
+
+// NewPatternNamespaceSetForInformer is like NewPatternNamespaceSet, but uses
+// the given Namespace informer, which must be run by the caller.
+func NewPatternNamespaceSetForInformer(informer cache.SharedIndexInformer, entries ...string) (PatternNamespaceSet, error) {
+	return newPatternNamespaceSet(informer, false, entries)
+}
+
+func newPatternNamespaceSet(informer cache.SharedIndexInformer, ownsInformer bool, entries []string,
+) (*patternNamespaceSet, error) {
+	var (
+		namespaces []string
+		patterns   []*namespacePattern
+	)
+
+	for _, entry := range entries {
+		p, err := parseEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if p != nil {
+			patterns = append(patterns, p)
+		} else {
+			namespaces = append(namespaces, entry)
+		}
+	}
+
+	s := &patternNamespaceSet{entries: append([]string(nil), entries...)}
+	s.watchedNamespaceSet = newWatchedNamespaceSet(informer, ownsInformer, s.resolver(namespaces, patterns))
+
+	return s, nil
+}
+
+// resolver returns a resolver for the given namespaces and the namespaces
+// matched by the given patterns, recording the matches.
+func (s *patternNamespaceSet) resolver(namespaces []string, patterns []*namespacePattern) namespaceResolver {
+	fixed := sets.NewSet(namespaces...)
+
+	return namespaceResolver{
+		all: func(store cache.Store) []string {
+			res := append([]string(nil), namespaces...)
+			matches := make(map[string]sets.Set, len(patterns))
+
+			for _, p := range patterns {
+				matches[p.entry] = sets.NewSet()
+			}
+
+			for _, obj := range store.List() {
+				ns, ok := obj.(*v1.Namespace)
+				if !ok {
+					continue
+				}
+
+				matched := false
+				for _, p := range patterns {
+					if p.match(ns.Name) {
+						matches[p.entry].Insert(ns.Name)
+						matched = true
+					}
+				}
+
+				if matched {
+					res = append(res, ns.Name)
+				}
+			}
+
+			s.lock.Lock()
+			s.matches = matches
+			s.lock.Unlock()
+
+			return res
+		},
+		object: func(obj interface{}, deleted bool) (string, bool, bool) {
+			ns, ok := obj.(*v1.Namespace)
+			if !ok {
+				return "", false, false
+			}
+
+			s.lock.Lock()
+			defer s.lock.Unlock()
+
+			matched := false
+			for _, p := range patterns {
+				if !deleted && p.match(ns.Name) {
+					s.matches[p.entry].Insert(ns.Name)
+					matched = true
+				} else {
+					s.matches[p.entry].Delete(ns.Name)
+				}
+			}
+
+			return ns.Name, matched || fixed.Contains(ns.Name), true
+		},
+	}
+}
+
+// SetNamespaces replaces the set's namespaces and patterns.  Malformed
+// patterns are skipped.
+func (s *patternNamespaceSet) SetNamespaces(entries []string) {
+	var (
+		valid      []string
+		namespaces []string
+		patterns   []*namespacePattern
+	)
+
+	for _, entry := range entries {
+		p, err := parseEntry(entry)
+		if err != nil {
+			klog.Errorf("Skipping namespace set entry: %v", err)
+			continue
+		}
+
+		if p != nil {
+			patterns = append(patterns, p)
+		} else {
+			namespaces = append(namespaces, entry)
+		}
+
+		valid = append(valid, entry)
+	}
+
+	s.lock.Lock()
+	s.entries = valid
+	s.lock.Unlock()
+
+	s.setResolver(s.resolver(namespaces, patterns))
+}
+
+// Entries returns the sorted namespaces and patterns the set was given.
+func (s *patternNamespaceSet) Entries() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := append([]string(nil), s.entries...)
+	sort.Strings(res)
+
+	return res
+}
+
+// PatternMatches returns the sorted namespaces matched by each pattern, as of
+// the last change to the set.  It is empty until the set is initialized.
+func (s *patternNamespaceSet) PatternMatches() map[string][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make(map[string][]string, len(s.matches))
+	for pattern, namespaces := range s.matches {
+		res[pattern] = namespaces.UnsortedList()
+		sort.Strings(res[pattern])
+	}
+
+	return res
+}
@@ -0,0 +1,85 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPatternNamespaceSet(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("tenant-a", nil),
+		newNamespace("team-x-prod", nil),
+		newNamespace("team-x-dev", nil),
+		newNamespace("other", nil),
+	)
+
+	namespaces, err := xnsinformers.NewPatternNamespaceSet(client, "istio-system", "tenant-*", "^team-[a-z]+-prod$")
+	if err != nil {
+		t.Fatalf("Failed to create namespace set: %v", err)
+	}
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	// Namespaces are held whether or not they exist.
+	recorder.waitFor(t, "add istio-system", "add tenant-a", "add team-x-prod")
+	recorder.reset()
+
+	expected := map[string][]string{
+		"tenant-*":           {"tenant-a"},
+		"^team-[a-z]+-prod$": {"team-x-prod"},
+	}
+	if matches := namespaces.PatternMatches(); !reflect.DeepEqual(matches, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", matches, expected)
+	}
+
+	// List holds the matched namespaces, never the patterns.
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{"istio-system", "team-x-prod", "tenant-a"}) {
+		t.Errorf("Expected namespaces [istio-system team-x-prod tenant-a], got: %v", list)
+	}
+
+	ctx := context.TODO()
+	if _, err := client.CoreV1().Namespaces().Create(ctx, newNamespace("tenant-b", nil), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	if err := client.CoreV1().Namespaces().Delete(ctx, "tenant-a", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete namespace: %v", err)
+	}
+
+	recorder.waitFor(t, "add tenant-b", "remove tenant-a")
+	recorder.reset()
+
+	// Replacing the entries skips malformed patterns.
+	namespaces.SetNamespaces([]string{"other", "team-*", "^("})
+
+	recorder.waitFor(t, "add other", "add team-x-dev", "remove istio-system", "remove tenant-b")
+
+	if entries := namespaces.Entries(); !reflect.DeepEqual(entries, []string{"other", "team-*"}) {
+		t.Errorf("Expected entries [other team-*], got: %v", entries)
+	}
+
+	expected = map[string][]string{"team-*": {"team-x-dev", "team-x-prod"}}
+	if matches := namespaces.PatternMatches(); !reflect.DeepEqual(matches, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", matches, expected)
+	}
+}
+
+func TestPatternNamespaceSetInvalid(t *testing.T) {
+	for _, entry := range []string{"^(", "tenant-["} {
+		if _, err := xnsinformers.NewPatternNamespaceSet(fake.NewSimpleClientset(), entry); err == nil {
+			t.Errorf("Expected an error for pattern %q", entry)
+		}
+	}
+}
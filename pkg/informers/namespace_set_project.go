@@ -0,0 +1,83 @@
+package informers
+
+import (
+	"context"
+
+	projectv1 "github.com/openshift/api/project/v1"
+	projectclient "github.com/openshift/client-go/project/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newProjectInformer returns an informer for all OpenShift Projects visible to
+// the client that match the given label selector.
+func newProjectInformer(client projectclient.Interface, selector labels.Selector) cache.SharedIndexInformer {
+	tweak := func(options *metav1.ListOptions) {
+		if selector != nil && !selector.Empty() {
+			options.LabelSelector = selector.String()
+		}
+	}
+
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				tweak(&options)
+				return client.ProjectV1().Projects().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				tweak(&options)
+				return client.ProjectV1().Projects().Watch(context.TODO(), options)
+			},
+		},
+		&projectv1.Project{},
+		0,
+		cache.Indexers{},
+	)
+}
+
+// NewProjectNamespaceSet returns a new NamespaceSet holding the namespaces of
+// the OpenShift Projects the client's user can access, since listing Projects
+// only returns those.  If selector is not nil, only the Projects whose labels
+// match it are included.  It watches Projects through the given client once it
+// is run.
+func NewProjectNamespaceSet(client projectclient.Interface, selector labels.Selector) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(newProjectInformer(client, selector), true, matchProjects(selector))
+}
+
+// NewProjectNamespaceSetForInformer is like NewProjectNamespaceSet, but uses
+// the given Project informer, which must be run by the caller.
+func NewProjectNamespaceSetForInformer(informer cache.SharedIndexInformer, selector labels.Selector) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(informer, false, matchProjects(selector))
+}
+
+// matchProjects returns a resolver for the names of the Project objects whose
+// labels match the given selector, which may be nil.
+func matchProjects(selector labels.Selector) namespaceResolver {
+	match := func(p *projectv1.Project) bool {
+		return selector == nil || selector.Matches(labels.Set(p.Labels))
+	}
+
+	return namespaceResolver{
+		all: func(store cache.Store) []string {
+			var res []string
+			for _, obj := range store.List() {
+				if p, ok := obj.(*projectv1.Project); ok && match(p) {
+					res = append(res, p.Name)
+				}
+			}
+
+			return res
+		},
+		object: func(obj interface{}, deleted bool) (string, bool, bool) {
+			p, ok := obj.(*projectv1.Project)
+			if !ok {
+				return "", false, false
+			}
+
+			return p.Name, !deleted && match(p), true
+		},
+	}
+}
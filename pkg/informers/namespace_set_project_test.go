@@ -0,0 +1,82 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	projectv1 "github.com/openshift/api/project/v1"
+	projectfake "github.com/openshift/client-go/project/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newProject(name string, projectLabels map[string]string) *projectv1.Project {
+	return &projectv1.Project{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: projectLabels}}
+}
+
+func TestProjectNamespaceSet(t *testing.T) {
+	client := projectfake.NewSimpleClientset(
+		newProject("ns1", map[string]string{"mesh": "member"}),
+		newProject("ns2", nil),
+	)
+
+	namespaces := xnsinformers.NewProjectNamespaceSet(client, nil)
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	recorder.waitFor(t, "add ns1", "add ns2")
+	recorder.reset()
+
+	ctx := context.TODO()
+	if _, err := client.ProjectV1().Projects().Create(ctx, newProject("ns3", nil), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	if err := client.ProjectV1().Projects().Delete(ctx, "ns1", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete project: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns3", "remove ns1")
+}
+
+func TestProjectNamespaceSetSelector(t *testing.T) {
+	client := projectfake.NewSimpleClientset(
+		newProject("ns1", map[string]string{"mesh": "member"}),
+		newProject("ns2", nil),
+	)
+
+	selector := labels.SelectorFromSet(labels.Set{"mesh": "member"})
+	namespaces := xnsinformers.NewProjectNamespaceSet(client, selector)
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	recorder.waitFor(t, "add ns1")
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{"ns1"}) {
+		t.Errorf("Expected namespaces [ns1], got: %v", list)
+	}
+
+	recorder.reset()
+
+	_, err := client.ProjectV1().Projects().Update(context.TODO(),
+		newProject("ns2", map[string]string{"mesh": "member"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update project: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns2")
+}
@@ -0,0 +1,64 @@
+package informers
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// InjectionLabel is the legacy label enabling injection by the default
+	// revision of Istio.
+	InjectionLabel = "istio-injection"
+
+	// RevisionLabel is the label selecting the revision of Istio for a
+	// namespace.
+	RevisionLabel = "istio.io/rev"
+
+	// DefaultRevision is the name of Istio's default revision.
+	DefaultRevision = "default"
+)
+
+// NamespaceRevision returns the Istio revision the given namespace belongs to,
+// or an empty string if it belongs to none.  Following Istio's precedence, the
+// istio-injection label wins over istio.io/rev when present: "enabled" selects
+// the default revision and any other value excludes the namespace.
+func NamespaceRevision(ns *v1.Namespace) string {
+	if injection, ok := ns.Labels[InjectionLabel]; ok {
+		if injection == "enabled" {
+			return DefaultRevision
+		}
+
+		return ""
+	}
+
+	return ns.Labels[RevisionLabel]
+}
+
+// NewRevisionNamespaceSet returns a new NamespaceSet holding the namespaces
+// that belong to the given Istio revision, as reported by NamespaceRevision.
+// An empty revision is the default revision.  A namespace whose labels move it
+// to another revision is removed from this set and added to the other
+// revision's set.  It watches Namespace objects through the given client once
+// it is run.
+func NewRevisionNamespaceSet(client kubernetes.Interface, revision string) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(newNamespaceInformer(client, nil), true, matchNamespaces(matchRevision(revision)))
+}
+
+// NewRevisionNamespaceSetForInformer is like NewRevisionNamespaceSet, but uses
+// the given Namespace informer, which must be run by the caller.  Sharing one
+// informer between the sets of several revisions avoids watching namespaces
+// more than once.
+func NewRevisionNamespaceSetForInformer(informer cache.SharedIndexInformer, revision string) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(informer, false, matchNamespaces(matchRevision(revision)))
+}
+
+func matchRevision(revision string) namespaceMatchFunc {
+	if revision == "" {
+		revision = DefaultRevision
+	}
+
+	return func(ns *v1.Namespace) bool {
+		return NamespaceRevision(ns) == revision
+	}
+}
@@ -0,0 +1,105 @@
+package informers_test
+
+import (
+	"context"
+	"testing"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespaceRevision(t *testing.T) {
+	testCases := []struct {
+		name     string
+		labels   map[string]string
+		expected string
+	}{
+		{name: "none", labels: nil, expected: ""},
+		{name: "injection enabled", labels: map[string]string{"istio-injection": "enabled"}, expected: "default"},
+		{name: "injection disabled", labels: map[string]string{"istio-injection": "disabled"}, expected: ""},
+		{name: "revision", labels: map[string]string{"istio.io/rev": "canary"}, expected: "canary"},
+		{
+			name:     "injection wins over revision",
+			labels:   map[string]string{"istio-injection": "enabled", "istio.io/rev": "canary"},
+			expected: "default",
+		},
+		{
+			name:     "disabled injection excludes revision",
+			labels:   map[string]string{"istio-injection": "disabled", "istio.io/rev": "canary"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := xnsinformers.NamespaceRevision(newNamespace("ns", tc.labels)); got != tc.expected {
+				t.Errorf("Expected revision %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRevisionNamespaceSet(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("ns1", map[string]string{"istio-injection": "enabled"}),
+		newNamespace("ns2", map[string]string{"istio.io/rev": "default"}),
+		newNamespace("ns3", map[string]string{"istio.io/rev": "canary"}),
+		newNamespace("ns4", map[string]string{"istio-injection": "disabled", "istio.io/rev": "canary"}),
+		newNamespace("ns5", nil),
+	)
+
+	factory := informers.NewSharedInformerFactory(client, 0)
+	informer := factory.Core().V1().Namespaces().Informer()
+
+	defaultSet := xnsinformers.NewRevisionNamespaceSetForInformer(informer, "")
+	canarySet := xnsinformers.NewRevisionNamespaceSetForInformer(informer, "canary")
+
+	defaultRecorder := &namespaceSetRecorder{}
+	defaultSet.AddHandler(defaultRecorder)
+
+	canaryRecorder := &namespaceSetRecorder{}
+	canarySet.AddHandler(canaryRecorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	factory.Start(stop)
+	go defaultSet.Run(stop)
+	go canarySet.Run(stop)
+
+	waitForInitialized(t, defaultSet)
+	waitForInitialized(t, canarySet)
+
+	defaultRecorder.waitFor(t, "add ns1", "add ns2")
+	canaryRecorder.waitFor(t, "add ns3")
+
+	defaultRecorder.reset()
+	canaryRecorder.reset()
+
+	// Moving ns2 to the canary revision removes it from the default set and
+	// adds it to the canary set.
+	ctx := context.TODO()
+	_, err := client.CoreV1().Namespaces().Update(ctx,
+		newNamespace("ns2", map[string]string{"istio.io/rev": "canary"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update namespace: %v", err)
+	}
+
+	defaultRecorder.waitFor(t, "remove ns2")
+	canaryRecorder.waitFor(t, "add ns2")
+
+	defaultRecorder.reset()
+	canaryRecorder.reset()
+
+	// Enabling injection on ns3 takes precedence over its revision label.
+	_, err = client.CoreV1().Namespaces().Update(ctx,
+		newNamespace("ns3", map[string]string{"istio-injection": "enabled", "istio.io/rev": "canary"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update namespace: %v", err)
+	}
+
+	defaultRecorder.waitFor(t, "add ns3")
+	canaryRecorder.waitFor(t, "remove ns3")
+}
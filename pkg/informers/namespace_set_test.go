@@ -3,7 +3,9 @@ package informers_test
 import (
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -187,3 +189,121 @@ func TestNamespaceSetContains(t *testing.T) {
 		})
 	}
 }
+
+func TestNamespaceSetReentrantHandler(t *testing.T) {
+	set := xnsinformers.NewNamespaceSet("a")
+
+	var events []string
+	set.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			events = append(events, "add "+ns)
+
+			// Handlers may call back into the set.  Changes they make are
+			// sent once the current one has been.
+			if ns == "b" {
+				set.SetNamespaces([]string{"c"})
+			}
+			_ = set.List()
+		},
+		RemoveFunc: func(ns string) {
+			events = append(events, "remove "+ns)
+		},
+	})
+
+	set.SetNamespaces([]string{"a", "b"})
+
+	expected := []string{"add a", "add b", "remove a", "remove b", "add c"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("%v ≠ %v", expected, events)
+	}
+
+	if list := set.List(); !reflect.DeepEqual(list, []string{"c"}) {
+		t.Errorf("%v ≠ %v", []string{"c"}, list)
+	}
+}
+
+func TestNamespaceSetRemoveHandler(t *testing.T) {
+	set := xnsinformers.NewNamespaceSet("a")
+	remover := set.(xnsinformers.NamespaceSetHandlerRemover)
+
+	var adds []string
+	handler := &xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			adds = append(adds, ns)
+		},
+	}
+
+	set.AddHandler(handler)
+
+	if err := remover.RemoveHandler(handler); err != nil {
+		t.Fatalf("Failed to remove handler: %v", err)
+	}
+
+	set.SetNamespaces([]string{"a", "b"})
+
+	if !reflect.DeepEqual(adds, []string{"a"}) {
+		t.Errorf("%v ≠ %v", []string{"a"}, adds)
+	}
+
+	if err := remover.RemoveHandler(handler); err == nil {
+		t.Errorf("Expected an error removing a handler twice")
+	}
+
+	if err := remover.RemoveHandler(xnsinformers.NamespaceSetHandlerFuncs{}); err == nil {
+		t.Errorf("Expected an error removing a handler of a type that isn't comparable")
+	}
+}
+
+func TestNamespaceSetFlush(t *testing.T) {
+	set := xnsinformers.NewNamespaceSet()
+	remover := set.(xnsinformers.NamespaceSetHandlerRemover)
+
+	var (
+		lock    sync.Mutex
+		adds    []string
+		release = make(chan struct{})
+		started = make(chan struct{})
+	)
+
+	set.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			if ns == "a" {
+				close(started)
+				<-release
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			adds = append(adds, ns)
+		},
+	})
+
+	go set.SetNamespaces([]string{"a"})
+	<-started
+
+	// The change is queued behind the one being sent, so this returns
+	// before it has been sent.
+	set.SetNamespaces([]string{"a", "b"})
+
+	flushed := make(chan struct{})
+	go func() {
+		remover.Flush()
+		close(flushed)
+	}()
+
+	select {
+	case <-flushed:
+		t.Fatalf("Flush returned before the changes were sent")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-flushed
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if !reflect.DeepEqual(adds, []string{"a", "b"}) {
+		t.Errorf("%v ≠ %v", []string{"a", "b"}, adds)
+	}
+}
@@ -0,0 +1,270 @@
+package informers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maistra/xns-informer/pkg/internal/sets"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// WatchedNamespaceSet is a NamespaceSet kept up to date from watched objects,
+// such as Namespaces.  It is uninitialized until Run has seen the informer for
+// those objects sync.  Calls to SetNamespaces are ignored.
+type WatchedNamespaceSet interface {
+	NamespaceSet
+
+	// Run runs the informer, unless it was supplied by the caller, and keeps
+	// the set up to date until the stop channel is closed.
+	Run(stopCh <-chan struct{})
+}
+
+// namespaceResolver resolves the namespaces of a set from the objects of an
+// informer.
+type namespaceResolver struct {
+	// all returns the namespaces for the set from the objects in the
+	// informer's cache.
+	all func(store cache.Store) []string
+
+	// object, if set, returns the namespace a single object stands for and
+	// whether it belongs in the set, given whether the object was deleted.
+	// Sets without it are resolved again from the whole cache whenever any
+	// of the objects change.
+	object func(obj interface{}, deleted bool) (namespace string, member bool, ok bool)
+}
+
+// namespaceMatchFunc reports whether a namespace belongs in a set.
+type namespaceMatchFunc func(ns *v1.Namespace) bool
+
+// watchedNamespaceSet is a NamespaceSet holding the namespaces resolved from
+// the objects of an informer, by a resolver which may be changed at any time.
+// The set is resolved from the whole cache once the informer syncs and when
+// the resolver changes, and after that from each changed object.
+type watchedNamespaceSet struct {
+	*namespaceSet
+
+	informer     cache.SharedIndexInformer
+	ownsInformer bool
+	registration cache.ResourceEventHandlerRegistration
+
+	// members holds the resolved namespaces, and version counts the changes
+	// to them.
+	lock     sync.Mutex
+	resolver namespaceResolver
+	synced   bool
+	members  sets.Set
+	version  uint64
+}
+
+var _ WatchedNamespaceSet = &watchedNamespaceSet{}
+
+// newNamespaceInformer returns an informer for all Namespace objects matching
+// the given label selector.
+func newNamespaceInformer(client kubernetes.Interface, selector labels.Selector) cache.SharedIndexInformer {
+	tweak := func(options *metav1.ListOptions) {
+		if selector != nil && !selector.Empty() {
+			options.LabelSelector = selector.String()
+		}
+	}
+
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				tweak(&options)
+				return client.CoreV1().Namespaces().List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				tweak(&options)
+				return client.CoreV1().Namespaces().Watch(context.TODO(), options)
+			},
+		},
+		&v1.Namespace{},
+		0,
+		cache.Indexers{},
+	)
+}
+
+// newWatchedNamespaceSet returns a new set holding the namespaces resolved by
+// the given resolver from the objects of the given informer.  If ownsInformer
+// is true, the informer is run by the set.
+func newWatchedNamespaceSet(informer cache.SharedIndexInformer, ownsInformer bool,
+	resolver namespaceResolver,
+) *watchedNamespaceSet {
+	s := &watchedNamespaceSet{
+		namespaceSet: &namespaceSet{},
+		informer:     informer,
+		ownsInformer: ownsInformer,
+		resolver:     resolver,
+	}
+
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.updateObject(obj, false) },
+		UpdateFunc: func(_, newObj interface{}) { s.updateObject(newObj, false) },
+		DeleteFunc: func(obj interface{}) { s.updateObject(obj, true) },
+	})
+	if err != nil {
+		klog.Errorf("Failed to add handler for namespace set: %v", err)
+	}
+
+	s.registration = registration
+
+	return s
+}
+
+// Run runs the informer if the set owns it, waits for it to sync,
+// and keeps the set up to date until the stop channel is closed.
+func (s *watchedNamespaceSet) Run(stopCh <-chan struct{}) {
+	if s.ownsInformer {
+		go s.informer.Run(stopCh)
+	}
+
+	if s.registration == nil || !cache.WaitForCacheSync(stopCh, s.registration.HasSynced) {
+		return
+	}
+
+	s.lock.Lock()
+	s.synced = true
+	s.lock.Unlock()
+
+	s.update()
+
+	<-stopCh
+}
+
+// SetNamespaces is ignored, since the set's namespaces are resolved from the
+// informer's objects.
+func (s *watchedNamespaceSet) SetNamespaces(namespaces []string) {
+	klog.Errorf("Ignoring SetNamespaces(%q) on a watched namespace set", namespaces)
+}
+
+// setResolver replaces the resolver and resolves the set again.
+func (s *watchedNamespaceSet) setResolver(resolver namespaceResolver) {
+	s.lock.Lock()
+	s.resolver = resolver
+	namespaces, version, ok := s.resolveAll()
+	s.lock.Unlock()
+
+	if ok {
+		s.setNamespacesAt(namespaces, version)
+	}
+}
+
+// update resolves the set from the informer's cache.
+func (s *watchedNamespaceSet) update() {
+	s.lock.Lock()
+	namespaces, version, ok := s.resolveAll()
+	s.lock.Unlock()
+
+	if ok {
+		s.setNamespacesAt(namespaces, version)
+	}
+}
+
+// updateObject updates the set after a change to the given object, resolving
+// only the namespace it stands for if the resolver can.
+func (s *watchedNamespaceSet) updateObject(obj interface{}, deleted bool) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	s.lock.Lock()
+
+	if s.resolver.object == nil {
+		namespaces, version, ok := s.resolveAll()
+		s.lock.Unlock()
+
+		if ok {
+			s.setNamespacesAt(namespaces, version)
+		}
+
+		return
+	}
+
+	if !s.synced {
+		s.lock.Unlock()
+		return
+	}
+
+	namespace, member, ok := s.resolver.object(obj, deleted)
+	if !ok || s.members.Contains(namespace) == member {
+		s.lock.Unlock()
+		return
+	}
+
+	if member {
+		s.members.Insert(namespace)
+	} else {
+		s.members.Delete(namespace)
+	}
+
+	s.version++
+	namespaces, version := s.members.UnsortedList(), s.version
+	s.lock.Unlock()
+
+	s.setNamespacesAt(namespaces, version)
+}
+
+// resolveAll resolves the set's members from the informer's cache once the
+// informer has synced, returning them and the version to set them at.  The
+// lock must be held.
+func (s *watchedNamespaceSet) resolveAll() ([]string, uint64, bool) {
+	if !s.synced {
+		return nil, 0, false
+	}
+
+	s.members = sets.NewSet(s.resolver.all(s.informer.GetStore())...)
+	s.version++
+
+	return s.members.UnsortedList(), s.version, true
+}
+
+// matchNamespaces returns a resolver for the names of the Namespace objects
+// matched by the given function.
+func matchNamespaces(match namespaceMatchFunc) namespaceResolver {
+	return namespaceResolver{
+		all: func(store cache.Store) []string {
+			var res []string
+			for _, obj := range store.List() {
+				if ns, ok := obj.(*v1.Namespace); ok && match(ns) {
+					res = append(res, ns.Name)
+				}
+			}
+
+			return res
+		},
+		object: func(obj interface{}, deleted bool) (string, bool, bool) {
+			ns, ok := obj.(*v1.Namespace)
+			if !ok {
+				return "", false, false
+			}
+
+			return ns.Name, !deleted && match(ns), true
+		},
+	}
+}
+
+// NewSelectorNamespaceSet returns a new NamespaceSet holding the namespaces
+// whose labels match the given selector.  It watches Namespace objects through
+// the given client, filtered by the selector, once it is run.
+func NewSelectorNamespaceSet(client kubernetes.Interface, selector labels.Selector) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(newNamespaceInformer(client, selector), true, matchNamespaces(matchSelector(selector)))
+}
+
+// NewSelectorNamespaceSetForInformer is like NewSelectorNamespaceSet, but uses
+// the given Namespace informer, which must be run by the caller.
+func NewSelectorNamespaceSetForInformer(informer cache.SharedIndexInformer, selector labels.Selector) WatchedNamespaceSet {
+	return newWatchedNamespaceSet(informer, false, matchNamespaces(matchSelector(selector)))
+}
+
+func matchSelector(selector labels.Selector) namespaceMatchFunc {
+	return func(ns *v1.Namespace) bool {
+		return selector.Matches(labels.Set(ns.Labels))
+	}
+}
@@ -0,0 +1,208 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newNamespace(name string, nsLabels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+}
+
+// namespaceSetRecorder records the add and remove events of a NamespaceSet.
+type namespaceSetRecorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *namespaceSetRecorder) OnAdd(namespace string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, "add "+namespace)
+}
+
+func (r *namespaceSetRecorder) OnRemove(namespace string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = append(r.events, "remove "+namespace)
+}
+
+func (r *namespaceSetRecorder) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.events = nil
+}
+
+// waitFor waits until the recorded events, in any order, are the given ones.
+func (r *namespaceSetRecorder) waitFor(t *testing.T, expected ...string) {
+	t.Helper()
+
+	sort.Strings(expected)
+
+	var got []string
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		got = append([]string(nil), r.events...)
+		sort.Strings(got)
+		return reflect.DeepEqual(got, expected), nil
+	})
+	if err != nil {
+		t.Fatalf("\n- got: %v\n- want: %v", got, expected)
+	}
+}
+
+// waitForInitialized waits until the given NamespaceSet is initialized.
+func waitForInitialized(t *testing.T, namespaces xnsinformers.NamespaceSet) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		return namespaces.Initialized(), nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for namespace set to be initialized")
+	}
+}
+
+func TestSelectorNamespaceSet(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("ns1", map[string]string{"mesh": "member"}),
+		newNamespace("ns2", nil),
+	)
+
+	selector := labels.SelectorFromSet(labels.Set{"mesh": "member"})
+	namespaces := xnsinformers.NewSelectorNamespaceSet(client, selector)
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	if namespaces.Initialized() {
+		t.Fatalf("Expected namespace set not to be initialized before it runs")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	recorder.waitFor(t, "add ns1")
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{"ns1"}) {
+		t.Errorf("Expected namespaces [ns1], got: %v", list)
+	}
+
+	recorder.reset()
+
+	ctx := context.TODO()
+
+	// Labelling ns2 adds it, and removing the label from ns1 removes it.
+	_, err := client.CoreV1().Namespaces().Update(ctx, newNamespace("ns2", map[string]string{"mesh": "member"}), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update namespace: %v", err)
+	}
+
+	_, err = client.CoreV1().Namespaces().Update(ctx, newNamespace("ns1", nil), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to update namespace: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns2", "remove ns1")
+	recorder.reset()
+
+	if err := client.CoreV1().Namespaces().Delete(ctx, "ns2", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete namespace: %v", err)
+	}
+
+	recorder.waitFor(t, "remove ns2")
+
+	if !namespaces.Initialized() {
+		t.Errorf("Expected namespace set to stay initialized when empty")
+	}
+}
+
+// listCountingInformer counts the calls to List on its store.
+type listCountingInformer struct {
+	cache.SharedIndexInformer
+	lists atomic.Int32
+}
+
+func (i *listCountingInformer) GetStore() cache.Store {
+	return &listCountingStore{Store: i.SharedIndexInformer.GetStore(), lists: &i.lists}
+}
+
+type listCountingStore struct {
+	cache.Store
+	lists *atomic.Int32
+}
+
+func (s *listCountingStore) List() []interface{} {
+	s.lists.Add(1)
+	return s.Store.List()
+}
+
+func TestSelectorNamespaceSetForInformer(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newNamespace("ns1", map[string]string{"mesh": "member"}),
+		newNamespace("ns2", nil),
+	)
+
+	informer := &listCountingInformer{
+		SharedIndexInformer: kubeinformers.NewSharedInformerFactory(client, 0).Core().V1().Namespaces().Informer(),
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{"mesh": "member"})
+	namespaces := xnsinformers.NewSelectorNamespaceSetForInformer(informer, selector)
+
+	recorder := &namespaceSetRecorder{}
+	namespaces.AddHandler(recorder)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	go namespaces.Run(stop)
+	waitForInitialized(t, namespaces)
+
+	recorder.waitFor(t, "add ns1")
+	recorder.reset()
+
+	// Changes to single namespaces don't resolve the whole set again.
+	_, err := client.CoreV1().Namespaces().Create(context.TODO(), newNamespace("ns3", map[string]string{"mesh": "member"}), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create namespace: %v", err)
+	}
+
+	if err := client.CoreV1().Namespaces().Delete(context.TODO(), "ns1", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Failed to delete namespace: %v", err)
+	}
+
+	recorder.waitFor(t, "add ns3", "remove ns1")
+
+	if lists := informer.lists.Load(); lists != 1 {
+		t.Errorf("Expected the store to be listed once, got %d", lists)
+	}
+
+	// The set's namespaces come from the informer, so SetNamespaces is
+	// ignored.
+	namespaces.SetNamespaces([]string{"other"})
+
+	if list := namespaces.List(); !reflect.DeepEqual(list, []string{"ns3"}) {
+		t.Errorf("Expected namespaces [ns3], got: %v", list)
+	}
+}
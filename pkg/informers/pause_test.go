@@ -0,0 +1,170 @@
+package informers_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// eventRecorder is an event handler that records events as strings of the form
+// "<type>:<name>:<resource version>", with updates showing both versions.
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) OnAdd(obj interface{}, _ bool) {
+	r.record("add:%s", describe(obj))
+}
+
+func (r *eventRecorder) OnUpdate(oldObj, newObj interface{}) {
+	r.record("update:%s->%s", describe(oldObj), mustAccessor(newObj).GetResourceVersion())
+}
+
+func (r *eventRecorder) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	r.record("delete:%s", describe(obj))
+}
+
+func (r *eventRecorder) record(format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+// reset returns the recorded events and clears them.
+func (r *eventRecorder) reset() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	events := r.events
+	r.events = nil
+
+	return events
+}
+
+// waitFor waits until at least n events have been recorded.
+func (r *eventRecorder) waitFor(t *testing.T, n int) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		return len(r.events) >= n, nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for %d events, got: %v", n, r.events)
+	}
+}
+
+func mustAccessor(obj interface{}) metav1.Object {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+func describe(obj interface{}) string {
+	m := mustAccessor(obj)
+	return m.GetName() + ":" + m.GetResourceVersion()
+}
+
+// newPod returns a pod with the given namespace and name.  Resource versions
+// are assigned by the fake controller source.
+func newPod(namespace, name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: namespace,
+		Name:      name,
+		UID:       types.UID(namespace + "-" + name),
+	}}
+}
+
+func TestMultiNamespaceInformerPauseNamespace(t *testing.T) {
+	source1 := fcache.NewFakeControllerSource()
+	source1.Add(newPod("ns1", "pod1"))
+	source1.Add(newPod("ns1", "pod2"))
+
+	source2 := fcache.NewFakeControllerSource()
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": source1,
+		"ns2": source2,
+	})
+
+	recorder := &eventRecorder{}
+	_, _ = informer.AddEventHandlerWithResyncPeriod(recorder, 0)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	recorder.waitFor(t, 2)
+	recorder.reset()
+
+	informer.PauseNamespace("ns1")
+
+	source1.Modify(newPod("ns1", "pod1"))
+	source1.Modify(newPod("ns1", "pod1"))
+	source1.Delete(newPod("ns1", "pod2"))
+	source1.Add(newPod("ns1", "pod3"))
+	source1.Delete(newPod("ns1", "pod3"))
+	source1.Add(newPod("ns1", "pod4"))
+
+	// Other namespaces are not affected.
+	source2.Add(newPod("ns2", "pod5"))
+	recorder.waitFor(t, 1)
+
+	// The cache keeps serving the paused namespace.
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		_, exists, err := informer.GetIndexer().GetByKey("ns1/pod4")
+		return exists, err
+	})
+	if err != nil {
+		t.Fatalf("Paused namespace cache was not updated: %v", err)
+	}
+
+	// Give the informer a moment to hand the last event to the handlers.
+	time.Sleep(100 * time.Millisecond)
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"add:pod5:1"}) {
+		t.Fatalf("Unexpected events while paused: %v", events)
+	}
+
+	informer.ResumeNamespace("ns1")
+
+	expected := []string{
+		"update:pod1:1->4",
+		"delete:pod2:5",
+		"add:pod4:8",
+	}
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", events, expected)
+	}
+
+	// Delivery continues normally after resuming.
+	source1.Modify(newPod("ns1", "pod4"))
+	recorder.waitFor(t, 1)
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, []string{"update:pod4:8->9"}) {
+		t.Errorf("Unexpected events after resuming: %v", events)
+	}
+}
@@ -0,0 +1,241 @@
+package informers
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// HandlerStats describes the work done by a handler's worker pool.
+type HandlerStats struct {
+	// QueueDepth is the number of events waiting for a worker.
+	QueueDepth int
+
+	// Delivered is the number of events handled so far.
+	Delivered uint64
+
+	// AverageLatency and MaxLatency measure the time from an event being
+	// queued until the handler finished with it.
+	AverageLatency time.Duration
+	MaxLatency     time.Duration
+}
+
+// WithWorkerPool runs the handler on its own pool of workers shared by all
+// namespaces, so a slow handler doesn't hold up the informers' processing.
+// Events are sharded across the workers by object key, which preserves their
+// order per object.  Each worker queues up to queueSize events, after which
+// delivery blocks until there is room.  The pool's queue depth and latency
+// are reported by HandlerStats.
+func WithWorkerPool(workers, queueSize int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.workers = workers
+		o.queueSize = queueSize
+	}
+}
+
+// poolItem is an event queued for a worker.
+type poolItem struct {
+	event  event
+	queued time.Time
+}
+
+// workerPool delivers events to a handler from a fixed number of workers,
+// each with its own bounded queue.  Events are assigned to workers by key.
+// The pool is started and stopped along with the informer; while it isn't
+// running, events are delivered directly.
+type workerPool struct {
+	handler   cache.ResourceEventHandler
+	workers   int
+	queueSize int
+
+	lock    sync.RWMutex
+	queues  []chan poolItem
+	stopCh  chan struct{}
+	running bool
+
+	// initialPending counts the queued events from informers' initial lists,
+	// so the handler's registration only reports synced once they are handled.
+	initialPending atomic.Int64
+
+	depth        atomic.Int64
+	delivered    atomic.Uint64
+	totalLatency atomic.Int64
+	maxLatency   atomic.Int64
+}
+
+var _ cache.ResourceEventHandler = &workerPool{}
+
+func newWorkerPool(handler cache.ResourceEventHandler, workers, queueSize int) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	return &workerPool{
+		handler:   handler,
+		workers:   workers,
+		queueSize: queueSize,
+	}
+}
+
+func (p *workerPool) OnAdd(obj interface{}, isInInitialList bool) {
+	p.push(newAddEvent(obj, isInInitialList))
+}
+
+func (p *workerPool) OnUpdate(oldObj, newObj interface{}) {
+	p.push(newUpdateEvent(oldObj, newObj))
+}
+
+func (p *workerPool) OnDelete(obj interface{}) {
+	p.push(newDeleteEvent(obj))
+}
+
+// push queues an event for the worker owning its key, blocking while that
+// worker's queue is full.
+func (p *workerPool) push(e event) {
+	p.lock.RLock()
+
+	if !p.running {
+		p.lock.RUnlock()
+		p.deliver(poolItem{event: e, queued: time.Now()})
+		return
+	}
+
+	queue, stopCh := p.queues[p.shard(e.key)], p.stopCh
+	p.lock.RUnlock()
+
+	p.depth.Add(1)
+	if e.isInInitialList {
+		p.initialPending.Add(1)
+	}
+
+	select {
+	case queue <- poolItem{event: e, queued: time.Now()}:
+	case <-stopCh:
+		p.depth.Add(-1)
+		if e.isInInitialList {
+			p.initialPending.Add(-1)
+		}
+	}
+}
+
+func (p *workerPool) shard(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(p.workers))
+}
+
+func (p *workerPool) deliver(item poolItem) {
+	item.event.deliver(p.handler)
+
+	latency := int64(time.Since(item.queued))
+
+	p.delivered.Add(1)
+	p.totalLatency.Add(latency)
+
+	for {
+		prev := p.maxLatency.Load()
+		if latency <= prev || p.maxLatency.CompareAndSwap(prev, latency) {
+			break
+		}
+	}
+}
+
+// start starts the workers, which are tracked by the given wait group.  Events
+// left over from a previous run are dropped, since a restarted informer lists
+// everything again.
+func (p *workerPool) start(wg *sync.WaitGroup) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.running {
+		return
+	}
+
+	p.queues = make([]chan poolItem, p.workers)
+	p.stopCh = make(chan struct{})
+	p.depth.Store(0)
+	p.initialPending.Store(0)
+
+	stopCh := p.stopCh
+
+	for n := range p.queues {
+		queue := make(chan poolItem, p.queueSize)
+		p.queues[n] = queue
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(queue, stopCh)
+		}()
+	}
+
+	p.running = true
+}
+
+func (p *workerPool) work(queue <-chan poolItem, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case item := <-queue:
+			p.depth.Add(-1)
+			p.deliver(item)
+
+			if item.event.isInInitialList {
+				p.initialPending.Add(-1)
+			}
+		}
+	}
+}
+
+// stop stops the workers.  Events still queued are dropped.
+func (p *workerPool) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if !p.running {
+		return
+	}
+
+	close(p.stopCh)
+	p.running = false
+}
+
+// hasSynced returns true if there are no queued events from initial lists.
+func (p *workerPool) hasSynced() bool {
+	return p.initialPending.Load() == 0
+}
+
+func (p *workerPool) stats() HandlerStats {
+	stats := HandlerStats{
+		QueueDepth: int(p.depth.Load()),
+		Delivered:  p.delivered.Load(),
+		MaxLatency: time.Duration(p.maxLatency.Load()),
+	}
+
+	if stats.Delivered > 0 {
+		stats.AverageLatency = time.Duration(p.totalLatency.Load() / int64(stats.Delivered))
+	}
+
+	return stats
+}
+
+// HandlerStats returns the stats of the worker pool for the handler with the
+// given registration.  It returns false if the handler doesn't belong to this
+// informer or wasn't added with WithWorkerPool.
+func (i *multiNamespaceInformer) HandlerStats(registration cache.ResourceEventHandlerRegistration) (HandlerStats, bool) {
+	d, ok := registration.(*eventHandlerData)
+	if !ok || d.informer != i || d.pool == nil {
+		return HandlerStats{}, false
+	}
+
+	return d.pool.stats(), true
+}
@@ -0,0 +1,151 @@
+package informers_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// slowRecorder is an eventRecorder that takes a while to handle each event.
+type slowRecorder struct {
+	eventRecorder
+	delay time.Duration
+}
+
+func (r *slowRecorder) OnAdd(obj interface{}, isInInitialList bool) {
+	time.Sleep(r.delay)
+	r.eventRecorder.OnAdd(obj, isInInitialList)
+}
+
+func (r *slowRecorder) OnUpdate(oldObj, newObj interface{}) {
+	time.Sleep(r.delay)
+	r.eventRecorder.OnUpdate(oldObj, newObj)
+}
+
+func TestMultiNamespaceInformerWorkerPool(t *testing.T) {
+	sources := map[string]*fcache.FakeControllerSource{
+		"ns1": fcache.NewFakeControllerSource(),
+		"ns2": fcache.NewFakeControllerSource(),
+	}
+
+	for ns, source := range sources {
+		for n := 0; n < 5; n++ {
+			source.Add(newPod(ns, fmt.Sprintf("pod%d", n)))
+		}
+	}
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": sources["ns1"],
+		"ns2": sources["ns2"],
+	})
+
+	recorder := &slowRecorder{delay: 20 * time.Millisecond}
+	registration, err := informer.AddEventHandlerWithOptions(recorder, 0, xnsinformers.WithWorkerPool(4, 10))
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	if _, ok := informer.HandlerStats(registration); !ok {
+		t.Fatalf("Expected stats for a handler with a worker pool")
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+
+	// The registration only syncs once the pool has handled the initial lists.
+	recorder.lock.Lock()
+	if len(recorder.events) != 10 {
+		t.Errorf("Expected 10 initial events once synced, got %d", len(recorder.events))
+	}
+	recorder.lock.Unlock()
+	recorder.reset()
+
+	// Updates to the same object are delivered in order.
+	for n := 0; n < 5; n++ {
+		sources["ns1"].Modify(newPod("ns1", "pod0"))
+	}
+
+	recorder.waitFor(t, 5)
+
+	expected := []string{
+		"update:pod0:1->6",
+		"update:pod0:6->7",
+		"update:pod0:7->8",
+		"update:pod0:8->9",
+		"update:pod0:9->10",
+	}
+
+	if events := recorder.reset(); !reflect.DeepEqual(events, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", events, expected)
+	}
+
+	var stats xnsinformers.HandlerStats
+	err = wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		stats, _ = informer.HandlerStats(registration)
+		return stats.Delivered == 15, nil
+	})
+	if err != nil || stats.QueueDepth != 0 || stats.MaxLatency < recorder.delay {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// listingRecorder is an eventRecorder that reads the informer's cache on each
+// delete, like a worker looking up related objects.
+type listingRecorder struct {
+	eventRecorder
+	informer cache.SharedIndexInformer
+}
+
+func (r *listingRecorder) OnDelete(obj interface{}) {
+	_ = r.informer.GetIndexer().ListKeys()
+	r.eventRecorder.OnDelete(obj)
+}
+
+func TestMultiNamespaceInformerWorkerPoolRemoveNamespace(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	for n := 0; n < 5; n++ {
+		source.Add(newPod("ns1", fmt.Sprintf("pod%d", n)))
+	}
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	// A single worker with a single slot makes delivering the deletes block
+	// on the worker, which reads the cache.
+	recorder := &listingRecorder{informer: informer}
+	registration, err := informer.AddEventHandlerWithOptions(recorder, 0, xnsinformers.WithWorkerPool(1, 1))
+	if err != nil {
+		t.Fatalf("Failed to add event handler: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced, registration.HasSynced)
+	recorder.reset()
+
+	removed := make(chan struct{})
+	go func() {
+		defer close(removed)
+		informer.RemoveNamespace("ns1")
+	}()
+
+	select {
+	case <-removed:
+	case <-time.After(wait.ForeverTestTimeout):
+		t.Fatalf("Timed out removing namespace")
+	}
+
+	recorder.waitFor(t, 5)
+}
@@ -0,0 +1,233 @@
+package informers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// priorityTierTimeout is the longest the informer waits for a priority tier to
+// sync before starting the next one, so a namespace that never syncs can't
+// keep lower priority namespaces from starting.
+const priorityTierTimeout = time.Minute
+
+// prioritySyncer is implemented by informers that can report whether the
+// namespaces of a given priority tier have synced.
+type prioritySyncer interface {
+	HasSyncedPriority(minPriority int) bool
+}
+
+// WaitForPrioritySync waits for the namespaces with at least the given priority
+// to sync in each of the given informers, or for the stop channel to close.
+// Informers without namespace priorities are waited for as a whole.  It returns
+// whether each informer's tier has synced.
+func WaitForPrioritySync[K comparable](stopCh <-chan struct{}, minPriority int,
+	informers map[K]cache.SharedIndexInformer,
+) map[K]bool {
+	hasSynced := func(informer cache.SharedIndexInformer) bool {
+		if p, ok := informer.(prioritySyncer); ok {
+			return p.HasSyncedPriority(minPriority)
+		}
+		return informer.HasSynced()
+	}
+
+	res := make(map[K]bool, len(informers))
+	for key, informer := range informers {
+		informer := informer
+		res[key] = cache.WaitForCacheSync(stopCh, func() bool {
+			return hasSynced(informer)
+		})
+	}
+
+	return res
+}
+
+// namespacePriority returns the priority of the given namespace, which is zero
+// unless the informer's namespace set is a PrioritizedNamespaceSet.
+func (i *multiNamespaceInformer) namespacePriority(namespace string) int {
+	if p, ok := i.namespaces.(PrioritizedNamespaceSet); ok {
+		return p.Priority(namespace)
+	}
+
+	return 0
+}
+
+// priorityTiers returns the informer's namespaces grouped by priority, highest
+// first.  The lock must be held.
+func (i *multiNamespaceInformer) priorityTiers() [][]string {
+	byPriority := make(map[int][]string)
+	for namespace := range i.informers {
+		priority := i.namespacePriority(namespace)
+		byPriority[priority] = append(byPriority[priority], namespace)
+	}
+
+	priorities := make([]int, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
+	tiers := make([][]string, 0, len(priorities))
+	for _, priority := range priorities {
+		tier := byPriority[priority]
+		sort.Strings(tier)
+		tiers = append(tiers, tier)
+	}
+
+	return tiers
+}
+
+// startTiers starts the informers of the given priority tiers one at a time,
+// waiting for each tier to sync, or for priorityTierTimeout, before starting
+// the next.  Namespaces that were removed, or started some other way, while
+// their tier was waiting are skipped.  It returns once every tier has been
+// started or the run ends.
+func (i *multiNamespaceInformer) startTiers(runStopCh <-chan struct{}, tiers [][]string) {
+	for n := 1; n < len(tiers); n++ {
+		i.waitForTier(runStopCh, tiers[n-1])
+
+		if !i.startTier(runStopCh, tiers[n]) {
+			return
+		}
+	}
+}
+
+// waitForTier waits until the given namespaces have synced, or are degraded or
+// gone, for at most priorityTierTimeout.
+func (i *multiNamespaceInformer) waitForTier(runStopCh <-chan struct{}, tier []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), priorityTierTimeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-runStopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := wait.PollUntilContextCancel(ctx, 100*time.Millisecond, true, func(ctx context.Context) (bool, error) {
+		i.lock.Lock()
+		defer i.lock.Unlock()
+
+		return i.namespacesSynced(tier), nil
+	})
+	if err != nil && !isClosed(runStopCh) {
+		klog.Warningf("Namespaces %v not synced after %v, starting the next priority tier", tier, priorityTierTimeout)
+	}
+}
+
+// startTier starts the informers of the given namespaces that are still
+// waiting for their tier.  It returns false if the run has ended.
+func (i *multiNamespaceInformer) startTier(runStopCh <-chan struct{}, tier []string) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if isClosed(runStopCh) {
+		return false
+	}
+
+	for _, namespace := range tier {
+		if !i.pendingNamespaces.Contains(namespace) {
+			continue
+		}
+
+		delete(i.pendingNamespaces, namespace)
+
+		if informer, ok := i.informers[namespace]; ok {
+			i.runInformer(namespace, informer)
+		}
+	}
+
+	klog.V(4).Infof("Started priority tier: %v", tier)
+
+	return true
+}
+
+// runByPriority runs the informer of a namespace added while the informer is
+// running once every namespace with a higher priority has synced, or after
+// priorityTierTimeout, like the tiers started by Run.  The lock must be held.
+func (i *multiNamespaceInformer) runByPriority(namespace string, informer cache.SharedIndexInformer) {
+	priority := i.namespacePriority(namespace)
+
+	var higher []string
+	for ns := range i.informers {
+		if ns != namespace && i.namespacePriority(ns) > priority {
+			higher = append(higher, ns)
+		}
+	}
+
+	// Namespaces still waiting for their tier haven't synced, so they hold
+	// this one back as well.
+	if i.namespacesSynced(higher) {
+		i.runInformer(namespace, informer)
+		return
+	}
+
+	sort.Strings(higher)
+	i.pendingNamespaces.Insert(namespace)
+
+	runStopCh := i.runStopCh
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+
+		i.waitForTier(runStopCh, higher)
+		i.startTier(runStopCh, []string{namespace})
+	}()
+}
+
+// namespacesSynced returns true if each of the given namespaces has synced, is
+// degraded, or is no longer tracked.  The lock must be held.
+func (i *multiNamespaceInformer) namespacesSynced(namespaces []string) bool {
+	i.updateDegradedNamespaces()
+
+	for _, namespace := range namespaces {
+		if i.degradedNamespaces.Contains(namespace) {
+			continue
+		}
+
+		if informer, ok := i.informers[namespace]; ok && !informer.HasSynced() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasSyncedPriority returns true once every namespace with at least the given
+// priority has synced, regardless of lower priority namespaces.  Namespaces
+// marked degraded are skipped, like in HasSynced.
+func (i *multiNamespaceInformer) HasSyncedPriority(minPriority int) bool {
+	if !i.namespaces.Initialized() {
+		return false
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	var tier []string
+	for namespace := range i.informers {
+		if i.namespacePriority(namespace) >= minPriority {
+			tier = append(tier, namespace)
+		}
+	}
+
+	return i.namespacesSynced(tier)
+}
+
+// isClosed returns true if the given channel is closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,146 @@
+package informers_test
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestPrioritizedNamespaceSetOrder(t *testing.T) {
+	namespaces := xnsinformers.NewPrioritizedNamespaceSet(map[string]int{
+		"ns-low":     0,
+		"ns-high":    10,
+		"ns-medium":  5,
+		"ns-alsolow": 0,
+	})
+
+	var added []string
+	namespaces.AddHandler(xnsinformers.NamespaceSetHandlerFuncs{
+		AddFunc: func(ns string) {
+			added = append(added, ns)
+		},
+	})
+
+	expected := []string{"ns-high", "ns-medium", "ns-alsolow", "ns-low"}
+	if !reflect.DeepEqual(added, expected) {
+		t.Errorf("\n- got: %v\n- want: %v", added, expected)
+	}
+
+	if p := namespaces.Priority("ns-medium"); p != 5 {
+		t.Errorf("Expected priority 5, got %d", p)
+	}
+
+	if p := namespaces.Priority("ns-unknown"); p != 0 {
+		t.Errorf("Expected priority 0, got %d", p)
+	}
+}
+
+func TestMultiNamespaceInformerPriorityTiers(t *testing.T) {
+	high := &failingListerWatcher{ListerWatcher: fcache.NewFakeControllerSource()}
+
+	lowSource := fcache.NewFakeControllerSource()
+	lowSource.Add(newPod("ns-low", "pod1"))
+	low := &countingListerWatcher{ListerWatcher: lowSource}
+
+	lws := map[string]cache.ListerWatcher{"ns-high": high, "ns-low": low}
+
+	informer := xnsinformers.NewMultiNamespaceInformerWithListerWatcher(
+		xnsinformers.NewPrioritizedNamespaceSet(map[string]int{"ns-high": 10, "ns-low": 0}),
+		&v1.Pod{},
+		0,
+		cache.Indexers{},
+		func(namespace string) cache.ListerWatcher {
+			return lws[namespace]
+		},
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+
+	// The low priority namespace isn't listed while the high priority one
+	// hasn't synced.
+	time.Sleep(300 * time.Millisecond)
+
+	if lists := atomic.LoadInt32(&low.lists); lists != 0 {
+		t.Fatalf("Expected no list calls for the low priority namespace, got %d", lists)
+	}
+
+	if informer.HasSyncedPriority(10) {
+		t.Fatalf("Expected high priority tier not to be synced")
+	}
+
+	high.ok.Store(true)
+
+	informers := map[string]cache.SharedIndexInformer{"pods": informer}
+	if synced := xnsinformers.WaitForPrioritySync(stop, 10, informers); !synced["pods"] {
+		t.Fatalf("Expected high priority tier to sync")
+	}
+
+	// The low priority namespace is started once the high one has synced.
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+	waitForKeys(t, informer, "ns-low/pod1")
+
+	if !informer.HasSyncedPriority(0) {
+		t.Errorf("Expected all tiers to be synced")
+	}
+}
+
+func TestMultiNamespaceInformerPriorityAddNamespace(t *testing.T) {
+	high := &failingListerWatcher{ListerWatcher: fcache.NewFakeControllerSource()}
+
+	lowSource := fcache.NewFakeControllerSource()
+	lowSource.Add(newPod("ns-low", "pod1"))
+	low := &countingListerWatcher{ListerWatcher: lowSource}
+
+	highCounter := &countingListerWatcher{ListerWatcher: high}
+	lws := map[string]cache.ListerWatcher{"ns-high": highCounter, "ns-low": low}
+
+	namespaces := xnsinformers.NewPrioritizedNamespaceSet(map[string]int{"ns-high": 10})
+	namespaces.SetPriorities(map[string]int{"ns-high": 10, "ns-low": 0})
+
+	informer := xnsinformers.NewMultiNamespaceInformerWithListerWatcher(
+		namespaces,
+		&v1.Pod{},
+		0,
+		cache.Indexers{},
+		func(namespace string) cache.ListerWatcher {
+			return lws[namespace]
+		},
+	)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		return atomic.LoadInt32(&highCounter.lists) > 0, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected the high priority namespace to be listed")
+	}
+
+	// A lower priority namespace added at runtime waits for the high
+	// priority one to sync.
+	namespaces.SetNamespaces([]string{"ns-high", "ns-low"})
+	time.Sleep(300 * time.Millisecond)
+
+	if lists := atomic.LoadInt32(&low.lists); lists != 0 {
+		t.Fatalf("Expected no list calls for the low priority namespace, got %d", lists)
+	}
+
+	high.ok.Store(true)
+
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+	waitForKeys(t, informer, "ns-low/pod1")
+}
@@ -0,0 +1,338 @@
+package informers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Snapshot files start with a magic string and a format version, followed by
+// the payload length, a SHA-256 checksum of the payload, and the JSON payload.
+const (
+	snapshotMagic   = "XNSSNAP"
+	snapshotVersion = 1
+)
+
+var (
+	// ErrSnapshotUnsupported is returned when saving or restoring a snapshot
+	// for an informer that wasn't created with a cache.ListerWatcher.
+	ErrSnapshotUnsupported = errors.New("informer does not support snapshots")
+
+	// ErrSnapshotCorrupt is returned when a snapshot can't be read.
+	ErrSnapshotCorrupt = errors.New("snapshot is corrupt")
+)
+
+// snapshotData is the payload of a snapshot file.
+type snapshotData struct {
+	ObjectType string              `json:"objectType"`
+	Namespaces []namespaceSnapshot `json:"namespaces"`
+}
+
+// namespaceSnapshot holds the cache contents for a single namespace.
+type namespaceSnapshot struct {
+	Namespace       string            `json:"namespace"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Objects         []json.RawMessage `json:"objects"`
+}
+
+// snapshotter is implemented by informers that support snapshots.
+type snapshotter interface {
+	SaveSnapshot(path string) error
+	RestoreSnapshot(path string) error
+}
+
+// SaveSnapshot writes the contents and resource version of each synced
+// namespace's cache to a file at the given path.  The file is replaced
+// atomically, so an interrupted save never leaves a partial snapshot behind.
+func (i *multiNamespaceInformer) SaveSnapshot(path string) error {
+	if i.newListerWatcher == nil {
+		return ErrSnapshotUnsupported
+	}
+
+	data, err := i.snapshot()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := writeSnapshot(w, data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// RestoreSnapshot reads a snapshot written by SaveSnapshot.  Namespaces found
+// in the snapshot serve their first list from it, so their informers only need
+// to watch for changes since the snapshot was taken.  If the snapshot is
+// corrupt, an error wrapping ErrSnapshotCorrupt is returned and the informer
+// lists everything as usual.
+func (i *multiNamespaceInformer) RestoreSnapshot(path string) error {
+	if i.newListerWatcher == nil {
+		return ErrSnapshotUnsupported
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := readSnapshot(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+
+	if expected := snapshotObjectType(i.exampleObject); data.ObjectType != expected {
+		return fmt.Errorf("%w: snapshot holds %s, not %s", ErrSnapshotCorrupt, data.ObjectType, expected)
+	}
+
+	restored := make(map[string]runtime.Object, len(data.Namespaces))
+	for _, ns := range data.Namespaces {
+		list := &metav1.List{
+			ListMeta: metav1.ListMeta{ResourceVersion: ns.ResourceVersion},
+			Items:    make([]runtime.RawExtension, 0, len(ns.Objects)),
+		}
+
+		for _, raw := range ns.Objects {
+			obj := reflect.New(reflect.TypeOf(i.exampleObject).Elem()).Interface().(runtime.Object)
+			if err := json.Unmarshal(raw, obj); err != nil {
+				return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+			}
+
+			list.Items = append(list.Items, runtime.RawExtension{Object: obj})
+		}
+
+		restored[ns.Namespace] = list
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.started {
+		return fmt.Errorf("cannot restore snapshot after the informer has started")
+	}
+
+	i.restored = restored
+
+	klog.V(2).Infof("Restored %d namespaces from snapshot: %s", len(restored), path)
+
+	return nil
+}
+
+// snapshot collects the contents of each synced namespace's cache.
+func (i *multiNamespaceInformer) snapshot() (*snapshotData, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	data := &snapshotData{ObjectType: snapshotObjectType(i.exampleObject)}
+
+	for namespace, informer := range i.informers {
+		if !informer.HasSynced() {
+			continue
+		}
+
+		// Read the resource version before listing.  The list may then be
+		// newer than the resource version, which just means some events are
+		// seen again after restoring, but never older.
+		ns := namespaceSnapshot{
+			Namespace:       namespace,
+			ResourceVersion: informer.LastSyncResourceVersion(),
+		}
+
+		for _, obj := range informer.GetStore().List() {
+			raw, err := json.Marshal(obj)
+			if err != nil {
+				return nil, err
+			}
+
+			ns.Objects = append(ns.Objects, raw)
+		}
+
+		data.Namespaces = append(data.Namespaces, ns)
+	}
+
+	return data, nil
+}
+
+// takeRestoredList returns the restored list for the given namespace, if any.
+// Each list is only returned once, so later relists go to the server.
+func (i *multiNamespaceInformer) takeRestoredList(namespace string) runtime.Object {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	list, ok := i.restored[namespace]
+	if !ok {
+		return nil
+	}
+
+	delete(i.restored, namespace)
+
+	return list
+}
+
+func writeSnapshot(w io.Writer, data *snapshotData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(payload)
+
+	var header bytes.Buffer
+	header.WriteString(snapshotMagic)
+	header.WriteByte(snapshotVersion)
+	_ = binary.Write(&header, binary.BigEndian, uint64(len(payload)))
+	header.Write(checksum[:])
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	_, err = w.Write(payload)
+	return err
+}
+
+func readSnapshot(r io.Reader) (*snapshotData, error) {
+	header := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrSnapshotCorrupt)
+	}
+
+	if version := header[len(snapshotMagic)]; version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrSnapshotCorrupt, version)
+	}
+
+	var length uint64
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	var checksum [sha256.Size]byte
+	if _, err := io.ReadFull(r, checksum[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	// Don't trust the length enough to allocate it all up front.
+	var payload bytes.Buffer
+	if n, err := io.CopyN(&payload, r, int64(length)); err != nil || uint64(n) != length {
+		return nil, fmt.Errorf("%w: truncated payload", ErrSnapshotCorrupt)
+	}
+
+	if sha256.Sum256(payload.Bytes()) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	data := &snapshotData{}
+	if err := json.Unmarshal(payload.Bytes(), data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	return data, nil
+}
+
+// snapshotObjectType returns the fully qualified type name of the given object.
+func snapshotObjectType(obj runtime.Object) string {
+	t := reflect.TypeOf(obj)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.PkgPath() + "." + t.Name()
+}
+
+// SnapshotFileName returns the name of the snapshot file used by informer
+// factories for informers of the given type.
+func SnapshotFileName(informerType reflect.Type) string {
+	for informerType.Kind() == reflect.Pointer {
+		informerType = informerType.Elem()
+	}
+
+	name := informerType.PkgPath() + "." + informerType.Name()
+
+	return strings.ReplaceAll(name, "/", "_") + ".snapshot"
+}
+
+// resourceSnapshotFileName returns the name of the snapshot file used by the
+// dynamic and metadata informer factories for the given resource.
+func resourceSnapshotFileName(gvr schema.GroupVersionResource) string {
+	return strings.Join([]string{gvr.Group, gvr.Version, gvr.Resource}, "_") + ".snapshot"
+}
+
+// SaveSnapshots saves a snapshot for each of the given informers that supports
+// snapshots to a file in dir named after its map key.
+func SaveSnapshots(dir string, informers map[string]cache.SharedIndexInformer) error {
+	var errs []error
+
+	for name, informer := range informers {
+		s, ok := informer.(snapshotter)
+		if !ok {
+			continue
+		}
+
+		if err := s.SaveSnapshot(filepath.Join(dir, name)); err != nil && !errors.Is(err, ErrSnapshotUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// RestoreSnapshots restores each of the given informers that supports
+// snapshots from the file in dir named after its map key.  Missing snapshots
+// are skipped.  Informers whose snapshot can't be restored list everything as
+// usual, so the returned errors are safe to log and ignore.
+func RestoreSnapshots(dir string, informers map[string]cache.SharedIndexInformer) error {
+	var errs []error
+
+	for name, informer := range informers {
+		s, ok := informer.(snapshotter)
+		if !ok {
+			continue
+		}
+
+		err := s.RestoreSnapshot(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, ErrSnapshotUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,171 @@
+package informers_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// countingListerWatcher counts the list calls made to a cache.ListerWatcher.
+type countingListerWatcher struct {
+	cache.ListerWatcher
+	lists int32
+}
+
+func (lw *countingListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	atomic.AddInt32(&lw.lists, 1)
+	return lw.ListerWatcher.List(options)
+}
+
+// newListerWatcherInformer is like newInformer, but creates the informer with
+// NewMultiNamespaceInformerWithListerWatcher.
+func newListerWatcherInformer(obj runtime.Object, lws map[string]cache.ListerWatcher) xnsinformers.MultiNamespaceInformer {
+	var namespaces []string
+	for ns := range lws {
+		namespaces = append(namespaces, ns)
+	}
+
+	return xnsinformers.NewMultiNamespaceInformerWithListerWatcher(
+		xnsinformers.NewNamespaceSet(namespaces...),
+		obj,
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		func(namespace string) cache.ListerWatcher {
+			return lws[namespace]
+		},
+	)
+}
+
+// waitForKeys waits until all of the given keys are in the informer's cache.
+func waitForKeys(t *testing.T, informer cache.SharedIndexInformer, keys ...string) {
+	t.Helper()
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 10*time.Millisecond, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		for _, key := range keys {
+			if _, exists, err := informer.GetIndexer().GetByKey(key); err != nil || !exists {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Timed out waiting for keys %v: %v", keys, err)
+	}
+}
+
+// saveSnapshot runs an informer for the given source until it syncs and saves
+// a snapshot of it to a new file.
+func saveSnapshot(t *testing.T, source cache.ListerWatcher) string {
+	t.Helper()
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": source})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	path := filepath.Join(t.TempDir(), "pods.snapshot")
+	if err := informer.SaveSnapshot(path); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+
+	return path
+}
+
+func TestMultiNamespaceInformerRestoreSnapshot(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+	source.Add(newPod("ns1", "pod2"))
+
+	path := saveSnapshot(t, source)
+
+	// This change is only seen by watching from the snapshot's version.
+	source.Add(newPod("ns1", "pod3"))
+
+	lw := &countingListerWatcher{ListerWatcher: source}
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": lw})
+
+	if err := informer.RestoreSnapshot(path); err != nil {
+		t.Fatalf("Failed to restore snapshot: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	waitForKeys(t, informer, "ns1/pod1", "ns1/pod2", "ns1/pod3")
+
+	if lists := atomic.LoadInt32(&lw.lists); lists != 0 {
+		t.Errorf("Expected no list calls after restoring, got %d", lists)
+	}
+}
+
+func TestMultiNamespaceInformerRestoreCorruptSnapshot(t *testing.T) {
+	source := fcache.NewFakeControllerSource()
+	source.Add(newPod("ns1", "pod1"))
+
+	path := saveSnapshot(t, source)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data[len(data)-2] ^= 0xff
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	lw := &countingListerWatcher{ListerWatcher: source}
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{"ns1": lw})
+
+	if err := informer.RestoreSnapshot(path); !errors.Is(err, xnsinformers.ErrSnapshotCorrupt) {
+		t.Fatalf("Expected corrupt snapshot error, got: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	// The informer falls back to a full list.
+	waitForKeys(t, informer, "ns1/pod1")
+
+	if lists := atomic.LoadInt32(&lw.lists); lists != 1 {
+		t.Errorf("Expected one list call, got %d", lists)
+	}
+}
+
+func TestMultiNamespaceInformerSnapshotUnsupported(t *testing.T) {
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": fcache.NewFakeControllerSource(),
+	})
+
+	path := filepath.Join(t.TempDir(), "pods.snapshot")
+
+	if err := informer.SaveSnapshot(path); !errors.Is(err, xnsinformers.ErrSnapshotUnsupported) {
+		t.Errorf("Expected unsupported error from SaveSnapshot, got: %v", err)
+	}
+
+	if err := informer.RestoreSnapshot(path); !errors.Is(err, xnsinformers.ErrSnapshotUnsupported) {
+		t.Errorf("Expected unsupported error from RestoreSnapshot, got: %v", err)
+	}
+}
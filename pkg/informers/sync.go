@@ -0,0 +1,154 @@
+package informers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// SyncReport describes the sync state of an informer after waiting for it with
+// WaitForCacheSyncWithTimeout.
+type SyncReport struct {
+	// Synced is true if the informer reports it has synced, which includes
+	// informers whose unsynced namespaces were marked degraded.
+	Synced bool
+
+	// UnsyncedNamespaces lists the namespaces that hadn't synced when the
+	// wait ended.  It is only filled in for multi-namespace informers.
+	UnsyncedNamespaces []string
+
+	// Degraded is true if the unsynced namespaces were marked degraded.
+	Degraded bool
+}
+
+// syncReporter is implemented by informers that can report and degrade their
+// unsynced namespaces.
+type syncReporter interface {
+	UnsyncedNamespaces() []string
+	MarkNamespacesDegraded(namespaces ...string)
+}
+
+// WaitForCacheSyncWithTimeout waits for the given informers to sync until the
+// timeout expires or the stop channel is closed, and returns a report for each
+// of them.  If markDegraded is true, the namespaces of multi-namespace
+// informers that haven't synced by then are marked degraded: the informers
+// report they have synced for the remaining namespaces, while the degraded
+// ones keep syncing in the background.
+func WaitForCacheSyncWithTimeout[K comparable](stopCh <-chan struct{}, timeout time.Duration, markDegraded bool,
+	informers map[K]cache.SharedIndexInformer,
+) map[K]SyncReport {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	_ = wait.PollUntilContextCancel(ctx, 100*time.Millisecond, true, func(ctx context.Context) (bool, error) {
+		for _, informer := range informers {
+			if !informer.HasSynced() {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+
+	res := make(map[K]SyncReport, len(informers))
+	for key, informer := range informers {
+		report := SyncReport{Synced: informer.HasSynced()}
+
+		if r, ok := informer.(syncReporter); ok && !report.Synced {
+			report.UnsyncedNamespaces = r.UnsyncedNamespaces()
+
+			if markDegraded && len(report.UnsyncedNamespaces) > 0 {
+				r.MarkNamespacesDegraded(report.UnsyncedNamespaces...)
+				report.Degraded = true
+				report.Synced = informer.HasSynced()
+			}
+		}
+
+		if !report.Synced || report.Degraded {
+			klog.Warningf("Informer %v not fully synced, unsynced namespaces: %v (degraded: %t)",
+				key, report.UnsyncedNamespaces, report.Degraded)
+		}
+
+		res[key] = report
+	}
+
+	return res
+}
+
+// UnsyncedNamespaces returns the sorted namespaces whose informers haven't
+// synced yet, including degraded ones.
+func (i *multiNamespaceInformer) UnsyncedNamespaces() []string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	var res []string
+	for namespace, informer := range i.informers {
+		if !informer.HasSynced() {
+			res = append(res, namespace)
+		}
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// MarkNamespacesDegraded marks the given namespaces as degraded.  HasSynced
+// ignores degraded namespaces until they have synced, at which point they are
+// no longer degraded.  This lets callers carry on with the namespaces that are
+// healthy while the others keep syncing in the background.
+func (i *multiNamespaceInformer) MarkNamespacesDegraded(namespaces ...string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for _, namespace := range namespaces {
+		if _, ok := i.informers[namespace]; ok {
+			i.degradedNamespaces.Insert(namespace)
+		}
+	}
+
+	klog.Warningf("Marked namespaces degraded: %v", namespaces)
+}
+
+// DegradedNamespaces returns the sorted namespaces that are degraded and still
+// haven't synced.
+func (i *multiNamespaceInformer) DegradedNamespaces() []string {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.updateDegradedNamespaces()
+
+	res := make([]string, 0, len(i.degradedNamespaces))
+	for namespace := range i.degradedNamespaces {
+		res = append(res, namespace)
+	}
+
+	sort.Strings(res)
+
+	return res
+}
+
+// updateDegradedNamespaces removes namespaces that have synced or are no
+// longer tracked from the degraded namespaces.  The lock must be held.
+func (i *multiNamespaceInformer) updateDegradedNamespaces() {
+	for namespace := range i.degradedNamespaces {
+		if informer, ok := i.informers[namespace]; !ok || informer.HasSynced() {
+			delete(i.degradedNamespaces, namespace)
+
+			if ok {
+				klog.Infof("Degraded namespace %q has synced", namespace)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package informers_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+// failingListerWatcher fails to list until it is told to succeed.
+type failingListerWatcher struct {
+	cache.ListerWatcher
+	ok atomic.Bool
+}
+
+func (lw *failingListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	if !lw.ok.Load() {
+		return nil, errors.New("access denied")
+	}
+
+	return lw.ListerWatcher.List(options)
+}
+
+func TestWaitForCacheSyncWithTimeout(t *testing.T) {
+	healthy := fcache.NewFakeControllerSource()
+	healthy.Add(newPod("ns1", "pod1"))
+
+	broken := &failingListerWatcher{ListerWatcher: fcache.NewFakeControllerSource()}
+
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": healthy,
+		"ns2": broken,
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+
+	informers := map[string]cache.SharedIndexInformer{"pods": informer}
+
+	report := xnsinformers.WaitForCacheSyncWithTimeout(stop, 500*time.Millisecond, false, informers)["pods"]
+	if expected := (xnsinformers.SyncReport{UnsyncedNamespaces: []string{"ns2"}}); !reflect.DeepEqual(report, expected) {
+		t.Fatalf("\n- got: %+v\n- want: %+v", report, expected)
+	}
+
+	if informer.HasSynced() {
+		t.Fatalf("Expected informer not to be synced")
+	}
+
+	report = xnsinformers.WaitForCacheSyncWithTimeout(stop, 100*time.Millisecond, true, informers)["pods"]
+	if expected := (xnsinformers.SyncReport{Synced: true, UnsyncedNamespaces: []string{"ns2"}, Degraded: true}); !reflect.DeepEqual(report, expected) {
+		t.Fatalf("\n- got: %+v\n- want: %+v", report, expected)
+	}
+
+	if !informer.HasSynced() {
+		t.Fatalf("Expected informer to be synced with ns2 degraded")
+	}
+
+	if degraded := informer.DegradedNamespaces(); !reflect.DeepEqual(degraded, []string{"ns2"}) {
+		t.Errorf("Expected ns2 to be degraded, got: %v", degraded)
+	}
+
+	// The degraded namespace keeps syncing in the background.
+	broken.ok.Store(true)
+
+	err := wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		return len(informer.DegradedNamespaces()) == 0, nil
+	})
+	if err != nil {
+		t.Errorf("Degraded namespace never synced: %v", informer.DegradedNamespaces())
+	}
+
+	if unsynced := informer.UnsyncedNamespaces(); len(unsynced) != 0 {
+		t.Errorf("Expected no unsynced namespaces, got: %v", unsynced)
+	}
+}
@@ -0,0 +1,263 @@
+package informers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/klog/v2"
+)
+
+// ErrWatchdogUnsupported is returned when configuring a watchdog for an
+// informer that wasn't created with a cache.ListerWatcher.
+var ErrWatchdogUnsupported = errors.New("informer does not support a watchdog")
+
+// WatchdogConfig configures the staleness watchdog of a multi-namespace
+// informer.
+type WatchdogConfig struct {
+	// StaleAfter is how long a namespace's watch may go without any events,
+	// including bookmarks, before the namespace is considered stale.
+	//
+	// The API server sends bookmarks to quiet watches only about once a
+	// minute, so StaleAfter should be well above a minute.  Shorter values
+	// make namespaces without changes relist over and over.
+	StaleAfter time.Duration
+
+	// CheckInterval is how often namespaces are checked for staleness.  It
+	// defaults to a quarter of StaleAfter.
+	CheckInterval time.Duration
+
+	// OnStale is called, if set, when a namespace is found to be stale, just
+	// before it is relisted.
+	OnStale func(namespace string, lastEvent time.Time)
+}
+
+// NamespaceWatchStats describes the watch activity of a single namespace.
+type NamespaceWatchStats struct {
+	// LastEvent is when the namespace last listed or saw a watch event.
+	LastEvent time.Time
+
+	// Stale is true if the namespace was found stale and hasn't seen any
+	// activity since.
+	Stale bool
+
+	// ForcedRelists counts the relists forced by the watchdog.
+	ForcedRelists int
+}
+
+// watchdog tracks the last list or watch event of each namespace, and makes
+// the watches of namespaces that have been quiet for too long fail with an
+// expired error, which makes their reflectors relist.
+type watchdog struct {
+	config WatchdogConfig
+
+	lock       sync.Mutex
+	namespaces map[string]*namespaceWatchState
+}
+
+// namespaceWatchState holds the watchdog's state for a single namespace.
+type namespaceWatchState struct {
+	NamespaceWatchStats
+	watches map[*expiringWatch]struct{}
+}
+
+func newWatchdog(config WatchdogConfig) *watchdog {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = config.StaleAfter / 4
+	}
+
+	return &watchdog{
+		config:     config,
+		namespaces: make(map[string]*namespaceWatchState),
+	}
+}
+
+// state returns the state for the given namespace.  The lock must be held.
+func (d *watchdog) state(namespace string) *namespaceWatchState {
+	s, ok := d.namespaces[namespace]
+	if !ok {
+		s = &namespaceWatchState{
+			NamespaceWatchStats: NamespaceWatchStats{LastEvent: time.Now()},
+			watches:             make(map[*expiringWatch]struct{}),
+		}
+		d.namespaces[namespace] = s
+	}
+
+	return s
+}
+
+// touch records activity for the given namespace.
+func (d *watchdog) touch(namespace string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	s := d.state(namespace)
+	s.LastEvent = time.Now()
+	s.Stale = false
+}
+
+// track returns a watch.Interface that records the events of the given watch
+// and can be expired by the watchdog.
+func (d *watchdog) track(namespace string, w watch.Interface) watch.Interface {
+	ww := newExpiringWatch(w, fmt.Sprintf("no watch events for namespace %q", namespace), func(watch.Event) bool {
+		d.touch(namespace)
+		return true
+	}, func(ww *expiringWatch) {
+		d.untrack(namespace, ww)
+	})
+
+	d.lock.Lock()
+	s := d.state(namespace)
+	s.LastEvent = time.Now()
+	s.watches[ww] = struct{}{}
+	d.lock.Unlock()
+
+	ww.start()
+
+	return ww
+}
+
+func (d *watchdog) untrack(namespace string, w *expiringWatch) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if s, ok := d.namespaces[namespace]; ok {
+		delete(s.watches, w)
+	}
+}
+
+// remove forgets the given namespace.
+func (d *watchdog) remove(namespace string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.namespaces, namespace)
+}
+
+// run checks for stale namespaces until the stop channel is closed.
+func (d *watchdog) run(stopCh <-chan struct{}) {
+	wait.Until(d.check, d.config.CheckInterval, stopCh)
+}
+
+// check expires the watches of every namespace that has been watching without
+// any events for longer than the staleness threshold.
+func (d *watchdog) check() {
+	type staleNamespace struct {
+		namespace string
+		lastEvent time.Time
+		watches   []*expiringWatch
+	}
+
+	var stale []staleNamespace
+
+	func() {
+		d.lock.Lock()
+		defer d.lock.Unlock()
+
+		for namespace, s := range d.namespaces {
+			if s.Stale || len(s.watches) == 0 || time.Since(s.LastEvent) < d.config.StaleAfter {
+				continue
+			}
+
+			s.Stale = true
+			s.ForcedRelists++
+
+			ns := staleNamespace{namespace: namespace, lastEvent: s.LastEvent}
+			for w := range s.watches {
+				ns.watches = append(ns.watches, w)
+			}
+
+			stale = append(stale, ns)
+		}
+	}()
+
+	for _, ns := range stale {
+		klog.Warningf("No watch events for namespace %q since %v, forcing a relist",
+			ns.namespace, ns.lastEvent)
+
+		if d.config.OnStale != nil {
+			d.config.OnStale(ns.namespace, ns.lastEvent)
+		}
+
+		for _, w := range ns.watches {
+			w.expire()
+		}
+	}
+}
+
+// stats returns the watch stats of every namespace.
+func (d *watchdog) stats() map[string]NamespaceWatchStats {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	res := make(map[string]NamespaceWatchStats, len(d.namespaces))
+	for namespace, s := range d.namespaces {
+		res[namespace] = s.NamespaceWatchStats
+	}
+
+	return res
+}
+
+// SetWatchdog enables a watchdog that detects watches which stay open without
+// delivering any events.  When a namespace goes without list or watch events,
+// including bookmarks, for longer than the configured threshold, its watch is
+// ended with an expired error so that only that namespace is relisted.  Watches
+// opened before the watchdog was enabled are only tracked once they restart.
+// This is only supported by informers created with a cache.ListerWatcher, and
+// can only be done once.
+func (i *multiNamespaceInformer) SetWatchdog(config WatchdogConfig) error {
+	if i.newListerWatcher == nil {
+		return ErrWatchdogUnsupported
+	}
+
+	if config.StaleAfter <= 0 {
+		return fmt.Errorf("watchdog staleness threshold must be positive")
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if i.watchdog != nil {
+		return fmt.Errorf("watchdog already set")
+	}
+
+	i.watchdog = newWatchdog(config)
+
+	if i.isRunning() {
+		i.runWatchdog()
+	}
+
+	return nil
+}
+
+// runWatchdog runs the watchdog until the current run ends.  The lock must be
+// held.
+func (i *multiNamespaceInformer) runWatchdog() {
+	runStopCh := i.runStopCh
+
+	i.wg.Add(1)
+	go func() {
+		defer i.wg.Done()
+		i.watchdog.run(runStopCh)
+	}()
+}
+
+// WatchStats returns the watch activity of each namespace tracked by the
+// watchdog, or nil if no watchdog is set.
+func (i *multiNamespaceInformer) WatchStats() map[string]NamespaceWatchStats {
+	if d := i.getWatchdog(); d != nil {
+		return d.stats()
+	}
+
+	return nil
+}
+
+func (i *multiNamespaceInformer) getWatchdog() *watchdog {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	return i.watchdog
+}
@@ -0,0 +1,105 @@
+package informers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xnsinformers "github.com/maistra/xns-informer/pkg/informers"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	fcache "k8s.io/client-go/tools/cache/testing"
+)
+
+func TestMultiNamespaceInformerWatchdog(t *testing.T) {
+	quiet := &countingListerWatcher{ListerWatcher: fcache.NewFakeControllerSource()}
+	busy := fcache.NewFakeControllerSource()
+	busyLW := &countingListerWatcher{ListerWatcher: busy}
+
+	informer := newListerWatcherInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": quiet,
+		"ns2": busyLW,
+	})
+
+	var (
+		lock  sync.Mutex
+		stale []string
+	)
+
+	err := informer.SetWatchdog(xnsinformers.WatchdogConfig{
+		StaleAfter:    300 * time.Millisecond,
+		CheckInterval: 50 * time.Millisecond,
+		OnStale: func(namespace string, _ time.Time) {
+			lock.Lock()
+			defer lock.Unlock()
+			stale = append(stale, namespace)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set watchdog: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go informer.Run(stop)
+	cache.WaitForCacheSync(stop, informer.HasSynced)
+
+	// Keep ns2 busy so it never goes stale.
+	go func() {
+		for n := 0; ; n++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+				busy.Add(newPod("ns2", fmt.Sprintf("pod%d", n)))
+			}
+		}
+	}()
+
+	// The quiet namespace is relisted.
+	err = wait.PollUntilContextTimeout(context.TODO(), 50*time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		return atomic.LoadInt32(&quiet.lists) >= 2, nil
+	})
+	if err != nil {
+		t.Fatalf("Expected quiet namespace to be relisted, got %d lists", atomic.LoadInt32(&quiet.lists))
+	}
+
+	stats := informer.WatchStats()
+	if stats["ns1"].ForcedRelists == 0 {
+		t.Errorf("Expected forced relists for ns1, got: %+v", stats["ns1"])
+	}
+
+	if stats["ns2"].ForcedRelists != 0 || atomic.LoadInt32(&busyLW.lists) != 1 {
+		t.Errorf("Expected no relists for ns2, got: %+v, %d lists", stats["ns2"], atomic.LoadInt32(&busyLW.lists))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, ns := range stale {
+		if ns != "ns1" {
+			t.Errorf("Unexpected stale namespace: %q", ns)
+		}
+	}
+
+	if len(stale) == 0 {
+		t.Errorf("Expected OnStale to be called for ns1")
+	}
+}
+
+func TestMultiNamespaceInformerWatchdogUnsupported(t *testing.T) {
+	informer := newInformer(&v1.Pod{}, map[string]cache.ListerWatcher{
+		"ns1": fcache.NewFakeControllerSource(),
+	})
+
+	err := informer.SetWatchdog(xnsinformers.WatchdogConfig{StaleAfter: time.Second})
+	if !errors.Is(err, xnsinformers.ErrWatchdogUnsupported) {
+		t.Errorf("Expected unsupported error, got: %v", err)
+	}
+}
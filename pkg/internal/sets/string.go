@@ -32,6 +32,37 @@ func (s Set) Insert(items ...string) Set {
 	return s
 }
 
+// Delete removes items from the set.
+func (s Set) Delete(items ...string) Set {
+	for _, item := range items {
+		delete(s, item)
+	}
+	return s
+}
+
+// Union returns a set of objects that are in either s or s2.
+func (s Set) Union(s2 Set) Set {
+	result := NewSet()
+	for key := range s {
+		result.Insert(key)
+	}
+	for key := range s2 {
+		result.Insert(key)
+	}
+	return result
+}
+
+// Intersection returns a set of objects that are in both s and s2.
+func (s Set) Intersection(s2 Set) Set {
+	result := NewSet()
+	for key := range s {
+		if _, exist := s2[key]; exist {
+			result.Insert(key)
+		}
+	}
+	return result
+}
+
 // Difference returns a set of objects that are not in s2
 // For example:
 // s = {a1, a2, a3}
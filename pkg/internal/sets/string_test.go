@@ -52,6 +52,30 @@ func TestDifference(t *testing.T) {
 	}
 }
 
+func TestDelete(t *testing.T) {
+	s := NewSet("a", "b", "c").Delete("b", "d")
+
+	if !s.Equals(NewSet("a", "c")) {
+		t.Errorf("Expected {a, c}: %v", s)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	u := NewSet("a", "b").Union(NewSet("b", "c"))
+
+	if !u.Equals(NewSet("a", "b", "c")) {
+		t.Errorf("Expected {a, b, c}: %v", u)
+	}
+}
+
+func TestIntersection(t *testing.T) {
+	i := NewSet("a", "b", "c").Intersection(NewSet("b", "c", "d"))
+
+	if !i.Equals(NewSet("b", "c")) {
+		t.Errorf("Expected {b, c}: %v", i)
+	}
+}
+
 func TestEquals(t *testing.T) {
 	tests := []struct {
 		name   string